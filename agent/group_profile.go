@@ -13,6 +13,7 @@ import (
 	"github.com/neuvector/neuvector/agent/policy"
 	"github.com/neuvector/neuvector/share"
 	"github.com/neuvector/neuvector/share/cluster"
+	"github.com/neuvector/neuvector/share/container"
 	"github.com/neuvector/neuvector/share/fsmon"
 	"github.com/neuvector/neuvector/share/utils"
 	log "github.com/sirupsen/logrus"
@@ -794,6 +795,36 @@ func applyProcGroupProfile(c *containerData) bool {
 	return false
 }
 
+// sensitiveMountDestinations lists container-relative mount destinations that
+// are high-risk if writable from inside the container: the well-known
+// container runtime sockets, and /proc and /sys, all of which can be used to
+// break out to the host.
+var sensitiveMountDestinations = []string{
+	"/var/run/docker.sock",
+	"/run/docker.sock",
+	"/var/run/dockershim.sock",
+	"/var/run/crio/crio.sock",
+	"/run/containerd/containerd.sock",
+	"/var/run/containerd/containerd.sock",
+	"/proc",
+	"/sys",
+}
+
+// detectSensitiveMounts returns the subset of mounts whose destination is a
+// known-sensitive host resource, see sensitiveMountDestinations.
+func detectSensitiveMounts(mounts []container.ContainerMetaMount) []string {
+	var found []string
+	for _, m := range mounts {
+		for _, dest := range sensitiveMountDestinations {
+			if m.Destination == dest {
+				found = append(found, dest)
+				break
+			}
+		}
+	}
+	return found
+}
+
 ////
 func applyFileGroupProfile(c *containerData) bool {
 	svc := makeLearnedGroupName(utils.NormalizeForURL(c.service))
@@ -821,6 +852,9 @@ func applyFileGroupProfile(c *containerData) bool {
 			Profile: file,
 			Rule:    access,
 		}
+		if c.info != nil {
+			config.SensitiveMounts = detectSensitiveMounts(c.info.Mounts)
+		}
 
 		//
 		fileWatcher.ContainerCleanup(c.pid, false)
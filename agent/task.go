@@ -173,7 +173,7 @@ func taskConfigAgent(conf *share.CLUSAgentConfig) {
 	log.WithFields(log.Fields{"config": conf}).Debug("")
 
 	// debug
-	var hasCPath, hasConn, hasCluster, hasMonitorTrace bool
+	var hasCPath, hasConn, hasCluster, hasMonitorTrace, hasFanotifyTrace, hasInotifyTrace bool
 	if conf.Debug == nil {
 		conf.Debug = make([]string, 0)
 	}
@@ -189,6 +189,10 @@ func taskConfigAgent(conf *share.CLUSAgentConfig) {
 			hasCluster = true
 		case "monitor":
 			hasMonitorTrace = true
+		case "monitor.fanotify":
+			hasFanotifyTrace = true
+		case "monitor.inotify":
+			hasInotifyTrace = true
 		default:
 			newDebug.Add(d)
 		}
@@ -205,7 +209,7 @@ func taskConfigAgent(conf *share.CLUSAgentConfig) {
 	}
 
 	prober.SetMonitorTrace(hasMonitorTrace)
-	fileWatcher.SetMonitorTrace(hasMonitorTrace)
+	fileWatcher.SetMonitorTrace(hasMonitorTrace, hasFanotifyTrace, hasInotifyTrace)
 
 	if !agentEnv.runWithController {
 		if hasCluster {
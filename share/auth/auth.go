@@ -399,14 +399,21 @@ func (a *remoteAuth) OIDCAuth(coidc *share.CLUSServerOIDC, tokenData *api.RESTAu
 		log.WithFields(log.Fields{"error": err}).Error("Failed to parse claims")
 	}
 
-	// Make UserInfo request
-	ctx, cancel := context.WithTimeout(context.Background(), oidcUserInfoTimeout)
-	defer cancel()
-
-	userInfo, err2 := oidc.UserInfoReq(ctx, coidc.UserInfoURL, oauth2.StaticTokenSource(token))
-	if err2 != nil {
-		log.WithFields(log.Fields{"error": err2}).Error("Failed on UserInfo request")
-		return claims, err
+	// Make UserInfo request. Some providers don't expose a userinfo endpoint at
+	// all, so treat that tier as optional rather than a hard failure and fall
+	// through to the other group-resolution tiers below.
+	var userInfo *oidc.UserInfo
+	if coidc.UserInfoURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), oidcUserInfoTimeout)
+		var err2 error
+		userInfo, err2 = oidc.UserInfoReq(ctx, coidc.UserInfoURL, oauth2.StaticTokenSource(token), keySet)
+		cancel()
+		if err2 != nil {
+			log.WithFields(log.Fields{"error": err2}).Debug("Failed on UserInfo request, falling back to other group sources")
+			userInfo = nil
+		}
+	} else {
+		log.Debug("oidc: provider has no userinfo endpoint, skipping tier")
 	}
 
 	// Check group info
@@ -420,18 +427,24 @@ func (a *remoteAuth) OIDCAuth(coidc *share.CLUSServerOIDC, tokenData *api.RESTAu
 		}
 	}
 
-	// Merge claims from UserInfo call
-	uiClaims := make(map[string]interface{})
-	if err2 = userInfo.Claims(&uiClaims); err2 != nil {
-		log.WithFields(log.Fields{"error": err2}).Error("Failed to parse UserInfo claims")
-		return claims, err
-	}
-
 	if claims == nil {
 		claims = make(map[string]interface{})
 	}
-	for k, v := range uiClaims {
-		claims[k] = v
+
+	// Merge claims from the UserInfo call, when we have one.
+	if userInfo != nil {
+		uiClaims := make(map[string]interface{})
+		if err2 := userInfo.Claims(&uiClaims); err2 != nil {
+			log.WithFields(log.Fields{"error": err2}).Error("Failed to parse UserInfo claims")
+			return claims, err
+		}
+		for k, v := range uiClaims {
+			claims[k] = v
+		}
+	}
+
+	if len(claims) == 0 {
+		return nil, errors.New("oidc: unable to resolve claims from id_token, userinfo, or distributed claim sources")
 	}
 
 	return claims, nil
@@ -0,0 +1,57 @@
+package oidc
+
+import "testing"
+
+func newTestVerifier(config *Config) *IDTokenVerifier {
+	return &IDTokenVerifier{config: config}
+}
+
+// TestCheckAudienceMultipleClientIDs covers the multi-audience intersection
+// logic added to checkAudience/acceptedClientIDs: a token's audience passing
+// as soon as it contains ClientID or any ExpectedAudiences entry, and failing
+// when it contains none of them.
+func TestCheckAudienceMultipleClientIDs(t *testing.T) {
+	v := newTestVerifier(&Config{
+		ClientID:          "web-client",
+		ExpectedAudiences: []string{"cli-client", "service-client"},
+	})
+
+	cases := []struct {
+		name    string
+		aud     []string
+		wantErr bool
+	}{
+		{"matches ClientID", []string{"web-client"}, false},
+		{"matches an ExpectedAudiences entry", []string{"service-client"}, false},
+		{"matches none of the configured client IDs", []string{"other-client"}, true},
+		{"empty audience", nil, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := v.checkAudience(c.aud)
+			if (err != nil) != c.wantErr {
+				t.Errorf("checkAudience(%v) error = %v, wantErr %v", c.aud, err, c.wantErr)
+			}
+		})
+	}
+}
+
+// TestCheckAuthorizedPartyMultipleClientIDs covers azp validation against the
+// same accepted-client-ID set as checkAudience, for a multi-audience token.
+func TestCheckAuthorizedPartyMultipleClientIDs(t *testing.T) {
+	v := newTestVerifier(&Config{
+		ClientID:          "web-client",
+		ExpectedAudiences: []string{"cli-client"},
+	})
+
+	aud := []string{"web-client", "cli-client"}
+	if err := v.checkAuthorizedParty(aud, "cli-client"); err != nil {
+		t.Errorf("expected azp matching an ExpectedAudiences entry to pass, got %v", err)
+	}
+	if err := v.checkAuthorizedParty(aud, "other-client"); err == nil {
+		t.Error("expected azp matching none of the accepted client IDs to fail")
+	}
+	if err := v.checkAuthorizedParty(aud, ""); err == nil {
+		t.Error("expected a multi-audience token with no azp claim to fail")
+	}
+}
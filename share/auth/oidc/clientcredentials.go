@@ -0,0 +1,33 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// ClientCredentialsToken performs an OAuth2 client credentials grant against
+// tokenURL for machine-to-machine calls that have no end user to
+// authenticate. It's a thin wrapper over clientcredentials.Config, but
+// resolves its HTTP client the same way the rest of this package does --
+// honoring the client (and its proxy/TLS config) installed on ctx via
+// ClientContext -- instead of defaulting to http.DefaultClient.
+func ClientCredentialsToken(ctx context.Context, tokenURL, clientID, clientSecret string, scopes []string) (*oauth2.Token, error) {
+	cfg := clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     tokenURL,
+		Scopes:       scopes,
+	}
+
+	token, err := cfg.Token(ctx)
+	if err != nil {
+		if rErr, ok := err.(*oauth2.RetrieveError); ok {
+			return nil, fmt.Errorf("oidc: client credentials request failed: %s", rErr.Body)
+		}
+		return nil, fmt.Errorf("oidc: client credentials request failed: %v", err)
+	}
+	return token, nil
+}
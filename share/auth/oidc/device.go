@@ -0,0 +1,185 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// defaultDevicePollInterval is used when the device authorization response
+// omits interval, per RFC 8628 section 3.2's default of 5 seconds.
+const defaultDevicePollInterval = 5 * time.Second
+
+// DeviceAuthResponse is the device authorization endpoint's response, RFC
+// 8628 section 3.2. Display UserCode and VerificationURI (or, if set,
+// VerificationURIComplete) to the user, then call PollToken to wait for them
+// to authorize the request on a secondary device.
+type DeviceAuthResponse struct {
+	DeviceCode              string
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+	ExpiresIn               time.Duration
+	Interval                time.Duration
+}
+
+// deviceAuthJSON is the wire format of DeviceAuthResponse.
+type deviceAuthJSON struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// StartDeviceFlow initiates the OAuth 2.0 Device Authorization Grant (RFC
+// 8628) against p.DeviceAuthURL for cfg's client, requesting scopes. It
+// returns an error if p never advertised a device_authorization_endpoint.
+func (p *Provider) StartDeviceFlow(ctx context.Context, cfg *oauth2.Config, scopes []string) (*DeviceAuthResponse, error) {
+	if p.DeviceAuthURL == "" {
+		return nil, errors.New("oidc: provider did not advertise a device_authorization_endpoint")
+	}
+
+	form := url.Values{"client_id": {cfg.ClientID}}
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+
+	_, resp, body, err := doRequestWithRetry(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", p.DeviceAuthURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if cfg.ClientSecret != "" {
+			req.SetBasicAuth(cfg.ClientID, cfg.ClientSecret)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, body)
+	}
+
+	var d deviceAuthJSON
+	if err := json.Unmarshal(body, &d); err != nil {
+		return nil, fmt.Errorf("oidc: failed to decode device authorization response: %v", err)
+	}
+
+	return &DeviceAuthResponse{
+		DeviceCode:              d.DeviceCode,
+		UserCode:                d.UserCode,
+		VerificationURI:         d.VerificationURI,
+		VerificationURIComplete: d.VerificationURIComplete,
+		ExpiresIn:               time.Duration(d.ExpiresIn) * time.Second,
+		Interval:                time.Duration(d.Interval) * time.Second,
+	}, nil
+}
+
+// PollToken polls cfg's token endpoint for resp per RFC 8628 sections 3.4 and
+// 3.5, honoring the granted Interval and slowing down further on
+// slow_down, until the user authorizes the request, denies it, the device
+// code expires, or ctx is done.
+func (resp *DeviceAuthResponse) PollToken(ctx context.Context, cfg *oauth2.Config) (*oauth2.Token, error) {
+	interval := resp.Interval
+	if interval <= 0 {
+		interval = defaultDevicePollInterval
+	}
+	var deadline time.Time
+	if resp.ExpiresIn > 0 {
+		deadline = time.Now().Add(resp.ExpiresIn)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil, errors.New("oidc: device code expired before the user authorized the request")
+		}
+
+		token, pollErr, err := pollDeviceToken(ctx, cfg, resp.DeviceCode)
+		if err != nil {
+			return nil, err
+		}
+		if token != nil {
+			return token, nil
+		}
+		if pollErr == "slow_down" {
+			interval += defaultDevicePollInterval
+		}
+	}
+}
+
+// pollDeviceToken makes a single device_code token request. pollErr is
+// "authorization_pending" or "slow_down" when the caller should keep
+// polling; any other failure is returned as err.
+func pollDeviceToken(ctx context.Context, cfg *oauth2.Config, deviceCode string) (token *oauth2.Token, pollErr string, err error) {
+	form := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {deviceCode},
+		"client_id":   {cfg.ClientID},
+	}
+
+	_, resp, body, err := doRequestWithRetry(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", cfg.Endpoint.TokenURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if cfg.ClientSecret != "" {
+			req.SetBasicAuth(cfg.ClientID, cfg.ClientSecret)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errBody struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(body, &errBody)
+		if errBody.Error == "authorization_pending" || errBody.Error == "slow_down" {
+			return nil, errBody.Error, nil
+		}
+		return nil, "", fmt.Errorf("%s: %s", resp.Status, body)
+	}
+
+	var t struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		IDToken      string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &t); err != nil {
+		return nil, "", fmt.Errorf("oidc: failed to decode device token response: %v", err)
+	}
+
+	oauthToken := &oauth2.Token{
+		AccessToken:  t.AccessToken,
+		TokenType:    t.TokenType,
+		RefreshToken: t.RefreshToken,
+	}
+	if t.ExpiresIn > 0 {
+		oauthToken.Expiry = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	if t.IDToken != "" {
+		oauthToken = oauthToken.WithExtra(map[string]interface{}{"id_token": t.IDToken})
+	}
+	return oauthToken, "", nil
+}
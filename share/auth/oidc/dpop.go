@@ -0,0 +1,132 @@
+package oidc
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+)
+
+// defaultDPoPProofMaxAge bounds how old a DPoP proof's iat claim may be
+// before VerifyDPoP rejects it as stale, limiting the window an intercepted
+// proof could be replayed in. RFC 9449 leaves the exact bound to the server;
+// a minute is generous enough to absorb clock skew and network latency
+// without meaningfully weakening the freshness check.
+const defaultDPoPProofMaxAge = time.Minute
+
+// dpopProofClaims is the payload of a DPoP proof JWT, RFC 9449 section 4.2.
+type dpopProofClaims struct {
+	HTTPMethod string   `json:"htm"`
+	HTTPURI    string   `json:"htu"`
+	IssuedAt   jsonTime `json:"iat"`
+	JTI        string   `json:"jti"`
+}
+
+// ErrDPoPProofMismatch is returned by VerifyDPoP when the proof doesn't bind
+// to the same key the token was confirmed with, doesn't match the request it
+// was presented alongside, or is no longer fresh.
+var ErrDPoPProofMismatch = errors.New("oidc: dpop proof does not match token or request")
+
+// VerifyDPoP checks that dpopProof demonstrates possession of the private
+// key bound to token via its "cnf"/"jkt" confirmation claim (RFC 9449). It
+// verifies the proof's own signature against its embedded jwk header, that
+// its "htm"/"htu" claims match httpMethod/httpURL, that it's fresh, and that
+// the thumbprint of its key matches token's jkt.
+//
+// token must have already passed Verify or VerifyVerbose; VerifyDPoP only
+// adds proof-of-possession checks on top of that and does not re-validate
+// the token's own signature, issuer, audience or expiry.
+func (v *IDTokenVerifier) VerifyDPoP(token *IDToken, dpopProof, httpMethod, httpURL string) error {
+	jkt, err := tokenJKT(token)
+	if err != nil {
+		return err
+	}
+
+	jws, err := jose.ParseSigned(dpopProof)
+	if err != nil {
+		return fmt.Errorf("oidc: malformed dpop proof: %v", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return fmt.Errorf("oidc: dpop proof must carry exactly one signature, got %d", len(jws.Signatures))
+	}
+
+	header := jws.Signatures[0].Header
+	if header.JSONWebKey == nil {
+		return errors.New("oidc: dpop proof is missing its embedded jwk header")
+	}
+	if typ, _ := header.ExtraHeaders[jose.HeaderKey("typ")].(string); typ != "dpop+jwt" {
+		return fmt.Errorf("oidc: dpop proof has unexpected typ %q, expected \"dpop+jwt\"", typ)
+	}
+
+	payload, err := jws.Verify(header.JSONWebKey)
+	if err != nil {
+		return fmt.Errorf("oidc: dpop proof signature invalid: %v", err)
+	}
+
+	var claims dpopProofClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return fmt.Errorf("oidc: failed to unmarshal dpop proof claims: %v", err)
+	}
+
+	if !strings.EqualFold(claims.HTTPMethod, httpMethod) {
+		return fmt.Errorf("%w: htm %q does not match %q", ErrDPoPProofMismatch, claims.HTTPMethod, httpMethod)
+	}
+	if !dpopURLsMatch(claims.HTTPURI, httpURL) {
+		return fmt.Errorf("%w: htu %q does not match %q", ErrDPoPProofMismatch, claims.HTTPURI, httpURL)
+	}
+
+	now := time.Now
+	if v.config.Now != nil {
+		now = v.config.Now
+	}
+	if age := now().Sub(time.Time(claims.IssuedAt)); age < 0 || age > defaultDPoPProofMaxAge {
+		return fmt.Errorf("%w: proof is not fresh (issued %v ago)", ErrDPoPProofMismatch, age)
+	}
+
+	thumb, err := header.JSONWebKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("oidc: failed to compute dpop key thumbprint: %v", err)
+	}
+	if base64.RawURLEncoding.EncodeToString(thumb) != jkt {
+		return fmt.Errorf("%w: key thumbprint does not match token's cnf.jkt", ErrDPoPProofMismatch)
+	}
+
+	return nil
+}
+
+// tokenJKT extracts the cnf.jkt confirmation claim from token.
+func tokenJKT(token *IDToken) (string, error) {
+	claims, err := token.Claims()
+	if err != nil {
+		return "", fmt.Errorf("oidc: failed to read token claims: %v", err)
+	}
+	cnf, ok := claims["cnf"].(map[string]interface{})
+	if !ok {
+		return "", errors.New("oidc: token has no cnf confirmation claim, it was not issued with proof-of-possession")
+	}
+	jkt, ok := cnf["jkt"].(string)
+	if !ok || jkt == "" {
+		return "", errors.New("oidc: token's cnf claim has no jkt thumbprint")
+	}
+	return jkt, nil
+}
+
+// dpopURLsMatch compares htu against the request URL, ignoring query and
+// fragment components, as required by RFC 9449 section 4.3.
+func dpopURLsMatch(htu, reqURL string) bool {
+	a, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(reqURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host) && a.Path == b.Path
+}
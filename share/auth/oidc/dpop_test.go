@@ -0,0 +1,100 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+)
+
+// signDPoPProof builds a compact DPoP proof JWT (RFC 9449 section 4.2) bound
+// to key, embedding its public part in the jwk header as required.
+func signDPoPProof(t *testing.T, key *ecdsa.PrivateKey, htm, htu string, iat time.Time) string {
+	jwk := jose.JSONWebKey{Key: key, Algorithm: string(jose.ES256)}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, &jose.SignerOptions{
+		ExtraHeaders: map[jose.HeaderKey]interface{}{
+			"typ": "dpop+jwt",
+			"jwk": jwk.Public(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"htm": htm,
+		"htu": htu,
+		"iat": iat.Unix(),
+		"jti": "proof1",
+	})
+	if err != nil {
+		t.Fatalf("marshal claims failed: %v", err)
+	}
+	signed, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	raw, err := signed.CompactSerialize()
+	if err != nil {
+		t.Fatalf("CompactSerialize failed: %v", err)
+	}
+	return raw
+}
+
+// tokenWithJKT builds an *IDToken carrying a cnf.jkt confirmation claim bound
+// to key's thumbprint.
+func tokenWithJKT(t *testing.T, key *ecdsa.PrivateKey) *IDToken {
+	jwk := jose.JSONWebKey{Key: &key.PublicKey, Algorithm: string(jose.ES256)}
+	thumb, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Thumbprint failed: %v", err)
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"cnf": map[string]interface{}{"jkt": base64.RawURLEncoding.EncodeToString(thumb)},
+	})
+	if err != nil {
+		t.Fatalf("marshal claims failed: %v", err)
+	}
+	return &IDToken{claims: claims}
+}
+
+// TestVerifyDPoP covers VerifyDPoP: a proof signed by the key bound to the
+// token's cnf.jkt, for the same method and URL, passes; a proof signed by a
+// different key fails with ErrDPoPProofMismatch.
+func TestVerifyDPoP(t *testing.T) {
+	boundKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	const method = "POST"
+	const url = "https://api.example.com/resource"
+	now := time.Now()
+
+	v := newTestVerifier(&Config{Now: func() time.Time { return now }})
+	token := tokenWithJKT(t, boundKey)
+
+	matching := signDPoPProof(t, boundKey, method, url, now)
+	if err := v.VerifyDPoP(token, matching, method, url); err != nil {
+		t.Errorf("expected a proof signed by the bound key to pass, got %v", err)
+	}
+
+	mismatched := signDPoPProof(t, otherKey, method, url, now)
+	err = v.VerifyDPoP(token, mismatched, method, url)
+	if err == nil {
+		t.Fatal("expected a proof signed by a different key to fail")
+	}
+	if !errors.Is(err, ErrDPoPProofMismatch) {
+		t.Errorf("expected ErrDPoPProofMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+package oidc
+
+import "testing"
+
+// TestCheckEmailVerified covers Config.RequireEmailVerified: a verified
+// email passes, an unverified one fails, and a token with no email claim at
+// all passes regardless, since RequireEmailVerified only guards against a
+// present-but-unverified email.
+func TestCheckEmailVerified(t *testing.T) {
+	v := newTestVerifier(&Config{RequireEmailVerified: true})
+
+	if err := v.checkEmailVerified("user@example.com", true); err != nil {
+		t.Errorf("expected a verified email to pass, got %v", err)
+	}
+	if err := v.checkEmailVerified("user@example.com", false); err == nil {
+		t.Error("expected an unverified email to fail")
+	}
+	if err := v.checkEmailVerified("", false); err != nil {
+		t.Errorf("expected a token with no email claim to pass, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultHTTPTimeout bounds an OIDC HTTP call -- discovery, token exchange,
+// userinfo, introspection, Azure group lookup -- when HTTPOptions.Timeout
+// isn't set.
+const defaultHTTPTimeout = 30 * time.Second
+
+// HTTPOptions configures the *http.Client NewHTTPContext builds, so every
+// OIDC HTTP call shares the same proxy and TLS behavior instead of each
+// caller wiring its own client into ClientContext.
+type HTTPOptions struct {
+	// Proxy, when set, is used for every request instead of the
+	// environment's HTTP_PROXY/HTTPS_PROXY/NO_PROXY.
+	Proxy *url.URL
+
+	// RootCAs, when set, replaces the system root CA pool for verifying the
+	// provider's TLS certificate, for IdPs behind a private CA.
+	RootCAs *x509.CertPool
+
+	// InsecureSkipVerify disables TLS certificate verification. Discouraged;
+	// prefer RootCAs.
+	InsecureSkipVerify bool
+
+	// Timeout bounds each request. Defaults to defaultHTTPTimeout.
+	Timeout time.Duration
+
+	// PinnedCertSHA256 optionally pins the IdP's TLS certificate: a
+	// connection is only accepted if the chain the server presents
+	// contains at least one certificate whose SHA-256 fingerprint appears
+	// in this set, so a compromised CA elsewhere in the trust store can't
+	// be used to MITM discovery/token/userinfo/introspection calls. This
+	// is on top of, not instead of, normal chain verification -- and it's
+	// still enforced even when combined with InsecureSkipVerify, since
+	// verifyCertPins runs as a VerifyPeerCertificate callback, which Go's
+	// TLS stack still invokes (and still honors a rejection from) when
+	// InsecureSkipVerify is set; only the verified chain built from the
+	// system/RootCAs pool is skipped in that mode. Entries are
+	// hex-encoded SHA-256 fingerprints of the DER-encoded certificate,
+	// case-insensitive. Empty disables pinning.
+	PinnedCertSHA256 []string
+}
+
+// NewHTTPContext builds an *http.Client from opts and installs it into ctx
+// via ClientContext, so Discover, UserInfoReq, GetAzureGroupInfo and the
+// introspection functions all issue requests through it instead of
+// http.DefaultClient.
+func NewHTTPContext(ctx context.Context, opts HTTPOptions) context.Context {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			RootCAs:            opts.RootCAs,
+			InsecureSkipVerify: opts.InsecureSkipVerify,
+		},
+	}
+	if opts.Proxy != nil {
+		transport.Proxy = http.ProxyURL(opts.Proxy)
+	}
+	if len(opts.PinnedCertSHA256) > 0 {
+		pins := make(map[string]bool, len(opts.PinnedCertSHA256))
+		for _, p := range opts.PinnedCertSHA256 {
+			pins[strings.ToLower(p)] = true
+		}
+		transport.TLSClientConfig.VerifyPeerCertificate = verifyCertPins(pins)
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+	return ClientContext(ctx, client)
+}
+
+// verifyCertPins returns a tls.Config.VerifyPeerCertificate callback
+// rejecting the connection unless the server's presented chain -- rawCerts,
+// in the order the server sent them -- contains at least one certificate
+// whose SHA-256 fingerprint is in pins. See HTTPOptions.PinnedCertSHA256.
+func verifyCertPins(pins map[string]bool) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		for _, raw := range rawCerts {
+			sum := sha256.Sum256(raw)
+			if pins[hex.EncodeToString(sum[:])] {
+				return nil
+			}
+		}
+		return fmt.Errorf("oidc: no certificate in the presented chain matches a configured pin")
+	}
+}
@@ -1,3 +1,4 @@
+//go:build !golint
 // +build !golint
 
 // Don't lint this file. We don't want to have to add a comment to each constant.
@@ -17,4 +18,10 @@ const (
 	PS256 = "PS256" // RSASSA-PSS using SHA256 and MGF1-SHA256
 	PS384 = "PS384" // RSASSA-PSS using SHA384 and MGF1-SHA384
 	PS512 = "PS512" // RSASSA-PSS using SHA512 and MGF1-SHA512
+
+	// JOSE symmetric signing algorithm value as defined by RFC 7518. See
+	// Config.HMACSecret before enabling it.
+	//
+	// see: https://tools.ietf.org/html/rfc7518#section-3.2
+	HS256 = "HS256" // HMAC using SHA-256
 )
@@ -2,6 +2,7 @@ package oidc
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -23,11 +24,35 @@ import (
 // updated.
 const keysExpiryDelta = 30 * time.Second
 
+// defaultRotationCooldown bounds how often an unrecognized kid is allowed to
+// trigger a refetch of an otherwise-unexpired key set, so a flood of tokens
+// signed with an unknown kid can't be used to hammer the provider's jwks_uri.
+const defaultRotationCooldown = 5 * time.Minute
+
 func NewRemoteKeySet(ctx context.Context, jwksURL string, now func() time.Time) *remoteKeySet {
+	return NewRemoteKeySetWithRotation(ctx, jwksURL, now, 0, 0)
+}
+
+// NewRemoteKeySetWithRotation is like NewRemoteKeySet but additionally allows
+// tuning key-rotation behavior. rotationCooldown bounds how often an unknown
+// kid triggers a refetch ahead of the normal expiry-driven refresh; zero uses
+// defaultRotationCooldown. maxCacheAge caps how long a fetched key set is
+// trusted regardless of the response's Cache-Control max-age; zero disables
+// the cap.
+func NewRemoteKeySetWithRotation(ctx context.Context, jwksURL string, now func() time.Time, rotationCooldown, maxCacheAge time.Duration) *remoteKeySet {
 	if now == nil {
 		now = time.Now
 	}
-	return &remoteKeySet{jwksURL: jwksURL, ctx: ctx, now: now}
+	if rotationCooldown <= 0 {
+		rotationCooldown = defaultRotationCooldown
+	}
+	return &remoteKeySet{
+		jwksURL:          jwksURL,
+		ctx:              ctx,
+		now:              now,
+		rotationCooldown: rotationCooldown,
+		maxCacheAge:      maxCacheAge,
+	}
 }
 
 type remoteKeySet struct {
@@ -35,6 +60,11 @@ type remoteKeySet struct {
 	ctx     context.Context
 	now     func() time.Time
 
+	// rotationCooldown and maxCacheAge tune key-rotation behavior; see
+	// NewRemoteKeySetWithRotation.
+	rotationCooldown time.Duration
+	maxCacheAge      time.Duration
+
 	// guard all other fields
 	mu sync.Mutex
 
@@ -45,6 +75,19 @@ type remoteKeySet struct {
 	// A set of cached keys and their expiry.
 	cachedKeys []jose.JSONWebKey
 	expiry     time.Time
+
+	// etag and lastModified are the provider's ETag/Last-Modified response
+	// headers from the last successful fetch, sent back as
+	// If-None-Match/If-Modified-Since on the next refresh so an unchanged
+	// key set costs a 304 instead of a full body. Providers that ignore
+	// these headers just answer 200 as normal, so this is safe to always
+	// send once known.
+	etag         string
+	lastModified string
+
+	// lastRotationFetch records the last time an unknown-kid refetch was
+	// allowed ahead of expiry, to enforce rotationCooldown.
+	lastRotationFetch time.Time
 }
 
 // inflight is used to wait on some in-flight request from multiple goroutines.
@@ -99,8 +142,13 @@ func (r *remoteKeySet) verify(ctx context.Context, jws *jose.JSONWebSignature) (
 	}
 
 	if !r.now().Add(keysExpiryDelta).After(expiry) {
-		// Keys haven't expired, don't refresh.
-		return nil, errors.New("failed to verify id token signature")
+		// Keys haven't expired. Still allow a rotation refetch for an
+		// unrecognized kid, but at most once per rotationCooldown, so a
+		// flood of tokens signed with an unknown kid can't be used to
+		// hammer the provider's jwks_uri.
+		if !r.allowRotationFetch() {
+			return nil, errors.New("failed to verify id token signature")
+		}
 	}
 
 	keys, err := r.keysFromRemote(ctx)
@@ -118,12 +166,61 @@ func (r *remoteKeySet) verify(ctx context.Context, jws *jose.JSONWebSignature) (
 	return nil, errors.New("failed to verify id token signature")
 }
 
+// staticKeySet verifies against a fixed set of keys parsed once at
+// construction, for air-gapped deployments that can't reach jwks_uri. It
+// implements the same keySet interface as remoteKeySet, so IDTokenVerifier
+// doesn't need to know which one it holds.
+type staticKeySet struct {
+	keys []jose.JSONWebKey
+}
+
+// NewStaticKeySet builds a keySet from a JWKS document already available
+// in-process -- loaded from a file baked into the image, a ConfigMap, etc --
+// instead of fetching it from the provider's jwks_uri. Pass the result to
+// NewVerifier in place of a *remoteKeySet.
+func NewStaticKeySet(jwks json.RawMessage) (*staticKeySet, error) {
+	var keySet jose.JSONWebKeySet
+	if err := json.Unmarshal(jwks, &keySet); err != nil {
+		return nil, fmt.Errorf("oidc: failed to decode static jwks: %v", err)
+	}
+	return &staticKeySet{keys: keySet.Keys}, nil
+}
+
+func (s *staticKeySet) verify(ctx context.Context, jws *jose.JSONWebSignature) ([]byte, error) {
+	keyID := ""
+	for _, sig := range jws.Signatures {
+		keyID = sig.Header.KeyID
+		break
+	}
+
+	for _, key := range s.keys {
+		if keyID == "" || key.KeyID == keyID {
+			if payload, err := jws.Verify(&key); err == nil {
+				return payload, nil
+			}
+		}
+	}
+	return nil, errors.New("failed to verify id token signature")
+}
+
 func (r *remoteKeySet) keysFromCache() (keys []jose.JSONWebKey, expiry time.Time) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	return r.cachedKeys, r.expiry
 }
 
+// allowRotationFetch reports whether an unrecognized kid may trigger a
+// refetch ahead of expiry, and records the attempt if so.
+func (r *remoteKeySet) allowRotationFetch() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.now().Sub(r.lastRotationFetch) < r.rotationCooldown {
+		return false
+	}
+	r.lastRotationFetch = r.now()
+	return true
+}
+
 // keysFromRemote syncs the key set from the remote set, records the values in the
 // cache, and returns the key set.
 func (r *remoteKeySet) keysFromRemote(ctx context.Context) ([]jose.JSONWebKey, error) {
@@ -167,18 +264,51 @@ func (r *remoteKeySet) keysFromRemote(ctx context.Context) ([]jose.JSONWebKey, e
 	}
 }
 
+// responseExpiry derives a key set's cache expiry from resp's cache-control
+// headers, defaulting to "expires immediately" if the provider doesn't send
+// any, and capped by r.maxCacheAge if set.
+func (r *remoteKeySet) responseExpiry(req *http.Request, resp *http.Response) time.Time {
+	expiry := r.now()
+	_, e, err := cachecontrol.CachableResponse(req, resp, cachecontrol.Options{})
+	if err == nil && e.After(expiry) {
+		expiry = e
+	}
+	if r.maxCacheAge > 0 {
+		if cap := r.now().Add(r.maxCacheAge); cap.Before(expiry) {
+			expiry = cap
+		}
+	}
+	return expiry
+}
+
 func (r *remoteKeySet) updateKeys() ([]jose.JSONWebKey, time.Time, error) {
 	req, err := http.NewRequest("GET", r.jwksURL, nil)
 	if err != nil {
 		return nil, time.Time{}, fmt.Errorf("oidc: can't create request: %v", err)
 	}
 
+	r.mu.Lock()
+	etag, lastModified, cachedKeys := r.etag, r.lastModified, r.cachedKeys
+	r.mu.Unlock()
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
 	resp, err := doRequest(r.ctx, req)
 	if err != nil {
 		return nil, time.Time{}, fmt.Errorf("oidc: get keys failed %v", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		// Provider confirmed our cached keys are still current -- keep them
+		// and just extend the expiry per this response's cache headers.
+		return cachedKeys, r.responseExpiry(req, resp), nil
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, time.Time{}, fmt.Errorf("unable to read response body: %v", err)
@@ -194,13 +324,10 @@ func (r *remoteKeySet) updateKeys() ([]jose.JSONWebKey, time.Time, error) {
 		return nil, time.Time{}, fmt.Errorf("oidc: failed to decode keys: %v %s", err, body)
 	}
 
-	// If the server doesn't provide cache control headers, assume the
-	// keys expire immediately.
-	expiry := r.now()
+	r.mu.Lock()
+	r.etag = resp.Header.Get("ETag")
+	r.lastModified = resp.Header.Get("Last-Modified")
+	r.mu.Unlock()
 
-	_, e, err := cachecontrol.CachableResponse(req, resp, cachecontrol.Options{})
-	if err == nil && e.After(expiry) {
-		expiry = e
-	}
-	return keySet.Keys, expiry, nil
+	return keySet.Keys, r.responseExpiry(req, resp), nil
 }
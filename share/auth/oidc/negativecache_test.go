@@ -0,0 +1,75 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+)
+
+func mustGenerateTestRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	return key
+}
+
+// countingKeySet counts calls to verify and always fails, standing in for a
+// real JWKS-backed keySet whose verification is the expensive "work" being
+// guarded against replay floods.
+type countingKeySet struct {
+	calls int
+}
+
+func (k *countingKeySet) verify(ctx context.Context, jws *jose.JSONWebSignature) ([]byte, error) {
+	k.calls++
+	return nil, errUnreachableCountingKeySet
+}
+
+var errUnreachableCountingKeySet = jose.ErrCryptoFailure
+
+// TestVerifyNegativeCacheSuppressesRepeatedWork covers the negative cache:
+// replaying the same invalid token many times within NegativeCacheTTL must
+// only invoke the keySet's (expensive) verification once, with every
+// subsequent call served from the cached failure.
+func TestVerifyNegativeCacheSuppressesRepeatedWork(t *testing.T) {
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": "https://issuer.example.com",
+		"aud": "client1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+	})
+	if err != nil {
+		t.Fatalf("marshal claims failed: %v", err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: mustGenerateTestRSAKey(t)}, nil)
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	signed, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	rawIDToken, err := signed.CompactSerialize()
+	if err != nil {
+		t.Fatalf("CompactSerialize failed: %v", err)
+	}
+
+	keys := &countingKeySet{}
+	v := NewVerifier(keys, &Config{ClientID: "client1"}, "https://issuer.example.com")
+
+	for i := 0; i < 20; i++ {
+		if _, err := v.Verify(context.Background(), rawIDToken); err == nil {
+			t.Fatalf("call %d: expected verification to fail", i)
+		}
+	}
+
+	if keys.calls != 1 {
+		t.Errorf("expected the keySet to be consulted exactly once across 20 replays, got %d", keys.calls)
+	}
+}
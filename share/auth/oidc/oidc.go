@@ -3,6 +3,7 @@ package oidc
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,11 +12,15 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	jose "github.com/go-jose/go-jose/v3"
 	"github.com/mitchellh/pointerstructure"
+	"github.com/pquerna/cachecontrol"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
@@ -67,10 +72,13 @@ func doRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
 
 // Provider represents an OpenID Connect server's configuration.
 type Provider struct {
-	Issuer      string
-	AuthURL     string
-	TokenURL    string
-	UserInfoURL string
+	Issuer        string
+	AuthURL       string
+	TokenURL      string
+	UserInfoURL   string
+	EndSessionURL string
+	DeviceAuthURL string
+	RevocationURL string
 
 	// Raw claims returned by the server.
 	rawClaims []byte
@@ -84,53 +92,216 @@ type cachedKeys struct {
 }
 
 type providerJSON struct {
-	Issuer      string `json:"issuer"`
-	AuthURL     string `json:"authorization_endpoint"`
-	TokenURL    string `json:"token_endpoint"`
-	JWKSURL     string `json:"jwks_uri"`
-	UserInfoURL string `json:"userinfo_endpoint"`
+	Issuer        string `json:"issuer"`
+	AuthURL       string `json:"authorization_endpoint"`
+	TokenURL      string `json:"token_endpoint"`
+	JWKSURL       string `json:"jwks_uri"`
+	UserInfoURL   string `json:"userinfo_endpoint"`
+	EndSessionURL string `json:"end_session_endpoint"`
+	DeviceAuthURL string `json:"device_authorization_endpoint"`
+	RevocationURL string `json:"revocation_endpoint"`
 }
 
 type Endpoints struct {
 	providerJSON
 }
 
+// LogoutURL builds an RP-initiated logout URL against the provider's
+// discovered end_session_endpoint, per the OpenID Connect RP-Initiated
+// Logout 1.0 spec. idTokenHint, if non-empty, is passed as id_token_hint so
+// the provider can identify which session to end without re-authenticating
+// the user. postLogoutRedirectURI, if non-empty, is passed as
+// post_logout_redirect_uri; most providers only honor it if it was
+// registered for the client beforehand. Returns an error if the provider
+// never advertised an end_session_endpoint.
+func (p *Provider) LogoutURL(idTokenHint, postLogoutRedirectURI string) (string, error) {
+	if p.EndSessionURL == "" {
+		return "", errors.New("oidc: provider did not advertise an end_session_endpoint")
+	}
+	u, err := url.Parse(p.EndSessionURL)
+	if err != nil {
+		return "", fmt.Errorf("oidc: invalid end_session_endpoint: %v", err)
+	}
+	q := u.Query()
+	if idTokenHint != "" {
+		q.Set("id_token_hint", idTokenHint)
+	}
+	if postLogoutRedirectURI != "" {
+		q.Set("post_logout_redirect_uri", postLogoutRedirectURI)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// DiscoverOptions controls how DiscoverWithOptions validates the issuer
+// returned by a provider's discovery document against the issuer that was
+// requested. The zero value matches Discover's strict default: the returned
+// issuer must equal the requested issuer exactly.
+type DiscoverOptions struct {
+	// InsecureSkipIssuerCheck disables the requested-vs-returned issuer
+	// comparison entirely. The provider-reported issuer is still recorded on
+	// Endpoints.Issuer so later token validation, which compares against the
+	// ID token's iss claim, uses the value the provider actually vouches
+	// for. Only set this for providers or reverse proxies known to misreport
+	// their issuer.
+	InsecureSkipIssuerCheck bool
+
+	// IgnoreIssuerTrailingSlash compares the requested and returned issuers
+	// after trimming a trailing "/" from each, tolerating providers and
+	// reverse proxies that add or drop one. Has no effect when
+	// InsecureSkipIssuerCheck is set.
+	IgnoreIssuerTrailingSlash bool
+}
+
 // NewProvider uses the OpenID Connect discovery mechanism to construct a Provider.
 //
 // The issuer is the URL identifier for the service. For example: "https://accounts.google.com"
 // or "https://login.salesforce.com".
 func Discover(ctx context.Context, issuer string) (*Endpoints, error) {
-	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
-	req, err := http.NewRequest("GET", wellKnown, nil)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := doRequest(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return DiscoverWithOptions(ctx, issuer, DiscoverOptions{})
+}
 
-	body, err := io.ReadAll(resp.Body)
+// DiscoverWithOptions is Discover with control over how the issuer returned
+// by the provider's discovery document is validated against issuer, see
+// DiscoverOptions.
+func DiscoverWithOptions(ctx context.Context, issuer string, opts DiscoverOptions) (*Endpoints, error) {
+	endpoints, _, err := discoverWithExpiry(ctx, issuer, opts)
+	return endpoints, err
+}
+
+// discoverWithExpiry performs the discovery request and additionally reports
+// the response's Cache-Control-derived expiry (the zero Time if the response
+// carries none), for use by ProviderCache.
+func discoverWithExpiry(ctx context.Context, issuer string, opts DiscoverOptions) (*Endpoints, time.Time, error) {
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, resp, body, err := doRequestWithRetry(ctx, func() (*http.Request, error) {
+		return http.NewRequest("GET", wellKnown, nil)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("unable to read response body: %v", err)
+		return nil, time.Time{}, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%s: %s", resp.Status, body)
+		return nil, time.Time{}, fmt.Errorf("%s: %s", resp.Status, body)
 	}
 
 	var p providerJSON
 	err = unmarshalResp(resp, body, &p)
 	if err != nil {
-		return nil, fmt.Errorf("oidc: failed to decode provider discovery object: %v", err)
+		return nil, time.Time{}, fmt.Errorf("oidc: failed to decode provider discovery object: %v", err)
 	}
 
-	if p.Issuer != issuer {
-		return nil, fmt.Errorf("oidc: issuer did not match the issuer returned by provider, expected %q got %q", issuer, p.Issuer)
+	if !opts.InsecureSkipIssuerCheck {
+		wantIssuer, gotIssuer := issuer, p.Issuer
+		if opts.IgnoreIssuerTrailingSlash {
+			wantIssuer = strings.TrimSuffix(wantIssuer, "/")
+			gotIssuer = strings.TrimSuffix(gotIssuer, "/")
+		}
+		if gotIssuer != wantIssuer {
+			return nil, time.Time{}, fmt.Errorf("oidc: issuer did not match the issuer returned by provider, expected %q got %q", issuer, p.Issuer)
+		}
+	}
+
+	var expiry time.Time
+	if _, e, err := cachecontrol.CachableResponse(req, resp, cachecontrol.Options{}); err == nil {
+		expiry = e
 	}
 
-	return &Endpoints{providerJSON: p}, nil
+	return &Endpoints{providerJSON: p}, expiry, nil
+}
+
+// defaultDiscoveryTTL is used to cache a discovery result whose response
+// carried no Cache-Control max-age.
+const defaultDiscoveryTTL = 10 * time.Minute
+
+type cachedEndpoints struct {
+	endpoints *Endpoints
+	expiry    time.Time
+}
+
+// ProviderCache caches OpenID Connect discovery results per issuer, so a
+// burst of concurrent logins against the same provider doesn't hammer its
+// /.well-known/openid-configuration endpoint. Entries are refreshed once
+// their Cache-Control max-age (or DefaultTTL, when the response carries
+// none) elapses. Concurrent lookups for an issuer that needs a refresh are
+// coalesced into a single in-flight request via singleflight.
+//
+// The zero value is ready to use.
+type ProviderCache struct {
+	// DefaultTTL caches a discovery result for this long when the response
+	// has no Cache-Control max-age. Defaults to defaultDiscoveryTTL when zero.
+	DefaultTTL time.Duration
+
+	// MaxStaleness, if positive, allows Discover to serve an expired cache
+	// entry when a refresh fails (e.g. the discovery endpoint is briefly
+	// unreachable), as long as the entry expired no more than MaxStaleness
+	// ago. This trades a small risk of stale provider metadata for keeping
+	// logins working through a brief IdP discovery outage, while JWKS
+	// verification (cached separately) continues to work as normal. Zero
+	// disables the fallback: a refresh failure with no fresh entry is
+	// returned to the caller as-is.
+	MaxStaleness time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedEndpoints
+	group   singleflight.Group
+}
+
+// Discover returns the cached discovery result for issuer, populating or
+// refreshing the cache entry via Discover when it is missing or expired. If
+// the refresh fails and MaxStaleness is set, a still-within-staleness expired
+// entry is returned instead of the error, see MaxStaleness.
+func (c *ProviderCache) Discover(ctx context.Context, issuer string) (*Endpoints, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[issuer]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expiry) {
+		return entry.endpoints, nil
+	}
+
+	v, err, _ := c.group.Do(issuer, func() (interface{}, error) {
+		endpoints, expiry, err := discoverWithExpiry(ctx, issuer, DiscoverOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		ttl := c.DefaultTTL
+		if ttl <= 0 {
+			ttl = defaultDiscoveryTTL
+		}
+		if !expiry.After(time.Now()) {
+			expiry = time.Now().Add(ttl)
+		}
+
+		c.mu.Lock()
+		if c.entries == nil {
+			c.entries = make(map[string]cachedEndpoints)
+		}
+		c.entries[issuer] = cachedEndpoints{endpoints: endpoints, expiry: expiry}
+		c.mu.Unlock()
+
+		return endpoints, nil
+	})
+	if err != nil {
+		if stale, staleOK := c.staleEndpoints(entry, ok); staleOK {
+			log.WithFields(log.Fields{"issuer": issuer, "error": err, "expiry": entry.expiry}).Warn("oidc: discovery failed, serving stale cached metadata")
+			return stale, nil
+		}
+		return nil, err
+	}
+	return v.(*Endpoints), nil
+}
+
+// staleEndpoints returns entry's endpoints if haveEntry is true and the entry
+// hasn't been expired for longer than MaxStaleness.
+func (c *ProviderCache) staleEndpoints(entry cachedEndpoints, haveEntry bool) (*Endpoints, bool) {
+	if !haveEntry || c.MaxStaleness <= 0 {
+		return nil, false
+	}
+	if time.Since(entry.expiry) > c.MaxStaleness {
+		return nil, false
+	}
+	return entry.endpoints, true
 }
 
 // Claims unmarshals raw fields returned by the server during discovery.
@@ -176,29 +347,46 @@ func (u *UserInfo) Claims(v interface{}) error {
 	return json.Unmarshal(u.claims, v)
 }
 
+// Groups extracts the "groups" claim as a string slice, saving every caller
+// from re-parsing u.claims. Like audience, some providers return a single
+// string instead of an array when there's only one group, so both forms are
+// accepted. Returns an empty slice, not an error, when the claim is absent.
+func (u *UserInfo) Groups() ([]string, error) {
+	var claims struct {
+		Groups audience `json:"groups"`
+	}
+	if err := u.Claims(&claims); err != nil {
+		return nil, err
+	}
+	return []string(claims.Groups), nil
+}
+
 // UserInfo uses the token source to query the provider's user info endpoint.
-func UserInfoReq(ctx context.Context, userInfoURL string, tokenSource oauth2.TokenSource) (*UserInfo, error) {
+// UserInfoReq queries the provider's user info endpoint. Per the OpenID
+// Connect spec, the response may be either plain JSON (Content-Type:
+// application/json) or a signed JWT (Content-Type: application/jwt); the
+// latter is only accepted, and its signature verified, when keySet is
+// non-nil. Pass the Provider's remoteKeySet (see NewRemoteKeySet) to support
+// providers that sign their userinfo response; nil is fine for providers
+// that always return plain JSON.
+func UserInfoReq(ctx context.Context, userInfoURL string, tokenSource oauth2.TokenSource, keySet keySet) (*UserInfo, error) {
 	if userInfoURL == "" {
 		return nil, errors.New("oidc: user info endpoint is not supported by this provider")
 	}
 
-	req, err := http.NewRequest("GET", userInfoURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("oidc: create GET request: %v", err)
-	}
-
 	token, err := tokenSource.Token()
 	if err != nil {
 		return nil, fmt.Errorf("oidc: get access token: %v", err)
 	}
-	token.SetAuthHeader(req)
 
-	resp, err := doRequest(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	_, resp, body, err := doRequestWithRetry(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", userInfoURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: create GET request: %v", err)
+		}
+		token.SetAuthHeader(req)
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -206,22 +394,106 @@ func UserInfoReq(ctx context.Context, userInfoURL string, tokenSource oauth2.Tok
 		return nil, fmt.Errorf("%s: %s", resp.Status, body)
 	}
 
+	payload := body
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/jwt" {
+		if keySet == nil {
+			return nil, errors.New("oidc: userinfo response is a signed JWT but no key set was provided to verify it")
+		}
+		jws, err := jose.ParseSigned(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("oidc: malformed userinfo JWT: %v", err)
+		}
+		payload, err = keySet.verify(ctx, jws)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: failed to verify userinfo JWT signature: %v", err)
+		}
+	}
+
 	var userInfo UserInfo
-	if err := json.Unmarshal(body, &userInfo); err != nil {
+	if err := json.Unmarshal(payload, &userInfo); err != nil {
 		return nil, fmt.Errorf("oidc: failed to decode userinfo: %v", err)
 	}
-	userInfo.claims = body
+	userInfo.claims = payload
 	return &userInfo, nil
 }
 
+// UserInfoWithRefresh is like UserInfoReq, but transparently refreshes token
+// via cfg first if it is expired, and returns the token actually used
+// alongside the UserInfo so the caller can persist it. This also covers
+// providers that rotate the refresh token itself on refresh, since
+// cfg.TokenSource returns the full updated token in that case.
+func UserInfoWithRefresh(ctx context.Context, userInfoURL string, cfg *oauth2.Config, token *oauth2.Token, keySet keySet) (*UserInfo, *oauth2.Token, error) {
+	refreshed, err := cfg.TokenSource(ctx, token).Token()
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc: refresh access token: %v", err)
+	}
+
+	userInfo, err := UserInfoReq(ctx, userInfoURL, oauth2.StaticTokenSource(refreshed), keySet)
+	if err != nil {
+		return nil, nil, err
+	}
+	return userInfo, refreshed, nil
+}
+
+// ErrAzureDistributedClaims is returned by GetGroupClaims when claimPath is
+// empty and the token carries an Azure AD distributed groups claim
+// (_claim_names/groups) rather than an inline one. Callers should fall
+// through to GetAzureGroupInfo, which resolves the claim via Microsoft Graph.
+var ErrAzureDistributedClaims = errors.New("oidc: groups are an Azure AD distributed claim")
+
+// GetGroupClaims extracts a string slice of group names from allClaims at
+// claimPath, an arbitrary JSON pointer -- e.g. "/groups" for Okta, or
+// "/realm_access/roles" for Keycloak's nested realm roles. If claimPath is
+// empty and the claims look like an Azure AD distributed groups claim,
+// ErrAzureDistributedClaims is returned so the caller can fall through to
+// GetAzureGroupInfo instead.
+func GetGroupClaims(allClaims map[string]interface{}, claimPath string) ([]string, error) {
+	if claimPath == "" {
+		if _, err := pointerstructure.Get(allClaims, fmt.Sprintf("/%s/%s", oidcClaimNames, oidcGroups)); err == nil {
+			return nil, ErrAzureDistributedClaims
+		}
+		return nil, errors.New("oidc: no group claim path configured")
+	}
+
+	val, err := pointerstructure.Get(allClaims, claimPath)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: group claim %q not found: %w", claimPath, err)
+	}
+
+	raw, ok := val.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("oidc: group claim %q is not a list: %v", claimPath, val)
+	}
+
+	groups := make([]string, 0, len(raw))
+	for _, g := range raw {
+		s, ok := g.(string)
+		if !ok {
+			return nil, fmt.Errorf("oidc: group claim %q contains a non-string entry: %v", claimPath, g)
+		}
+		groups = append(groups, s)
+	}
+	return groups, nil
+}
+
 // GetAzureGroupInfo gets Azure's group information following _claim_sources.
+// It's a thin wrapper over ResolveDistributedClaim for the "groups" claim.
 func GetAzureGroupInfo(ctx context.Context, allClaims map[string]interface{}, tokenSource oauth2.TokenSource) (interface{}, error) {
+	return ResolveDistributedClaim(ctx, allClaims, oidcGroups, tokenSource)
+}
 
-	// Here we check if below conditions are met:
-	// 1. claims.iss contains "login.microsoftonline.com", which means it's coming from Azure AD.
-	// 2. No groups is provided.
-	// 3. _claim_sources is available.
-	// If they're all met at the same time, we try to get information from the endpoint specified in _claim_sources.
+// ResolveDistributedClaim resolves an Azure AD distributed claim -- one too
+// large to inline in the token, so Azure instead points to it via
+// _claim_names/_claim_sources (see
+// https://learn.microsoft.com/en-us/azure/active-directory/develop/id-token-claims-reference#the-_claim_names-and-_claim_sources-claims).
+// claimName is the claim to resolve, e.g. "groups" or "roles".
+//
+// Here we check if below conditions are met:
+// 1. claims.iss contains "login.microsoftonline.com", which means it's coming from Azure AD.
+// 2. claimName isn't provided inline.
+// 3. _claim_sources is available.
+// If they're all met at the same time, we try to get information from the endpoint specified in _claim_sources.
+func ResolveDistributedClaim(ctx context.Context, allClaims map[string]interface{}, claimName string, tokenSource oauth2.TokenSource) (interface{}, error) {
 	iss, err := pointerstructure.Get(allClaims, "/iss")
 	if err != nil {
 		return nil, fmt.Errorf("failed to find issuer: %w", err)
@@ -235,9 +507,9 @@ func GetAzureGroupInfo(ctx context.Context, allClaims map[string]interface{}, to
 		return nil, fmt.Errorf("not recognized issuer: %s", issuer)
 	}
 
-	src, err := pointerstructure.Get(allClaims, fmt.Sprintf("/%s/%s", oidcClaimNames, oidcGroups))
+	src, err := pointerstructure.Get(allClaims, fmt.Sprintf("/%s/%s", oidcClaimNames, claimName))
 	if err != nil {
-		return nil, fmt.Errorf("failed to find group claim name: %w", err)
+		return nil, fmt.Errorf("failed to find %s claim name: %w", claimName, err)
 	}
 
 	srcname, ok := src.(string)
@@ -331,6 +603,11 @@ type IDToken struct {
 	// A unique string which identifies the end user.
 	Subject string
 
+	// The party to which this token was issued, from the azp claim. Only
+	// required to be present by OIDC when Audience contains more than one
+	// value; see IDTokenVerifier's azp check in checkAuthorizedParty.
+	AuthorizedParty string
+
 	// Expiry of the token. Ths package will not process tokens that have
 	// expired unless that validation is explicitly turned off.
 	Expiry time.Time
@@ -343,6 +620,22 @@ type IDToken struct {
 	// and it's the user's responsibility to ensure it contains a valid value.
 	Nonce string
 
+	// The Authentication Context Class Reference the provider asserts this
+	// authentication satisfied, from the acr claim. Empty if the provider
+	// didn't include one. See IDTokenVerifier's Config.RequiredACR.
+	ACR string
+
+	// The Authentication Methods References the provider asserts were used
+	// for this login, from the amr claim, e.g. "pwd", "mfa", "hwk". Empty if
+	// the provider didn't include one. See IDTokenVerifier's Config.RequiredAMR.
+	AuthMethods []string
+
+	// The Google Workspace hosted domain the user's account belongs to,
+	// from the hd claim. Empty for personal Google accounts, or for
+	// providers other than Google that don't set it. See
+	// IDTokenVerifier's Config.RequiredHostedDomain.
+	HostedDomain string
+
 	// Raw payload of the id_token.
 	claims []byte
 }
@@ -374,13 +667,63 @@ func (i *IDToken) Claims() (map[string]interface{}, error) {
 	return nil, errors.New("oidc: unable to parse claims")
 }
 
+// StandardClaims holds the common OpenID Connect profile claims -- email,
+// preferred_username, name and email_verified -- that callers otherwise
+// extract from IDToken.Claims() by hand, e.g. for SSO group/attribute
+// mapping.
+type StandardClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+}
+
+// StandardClaims unmarshals the token's email, preferred_username, name and
+// email_verified claims, reusing the raw payload saved for Claims. A
+// provider that omits email_verified leaves it false rather than causing an
+// error.
+func (i *IDToken) StandardClaims() (*StandardClaims, error) {
+	if i.claims == nil {
+		return nil, errors.New("oidc: claims not set")
+	}
+
+	var c StandardClaims
+	if err := json.Unmarshal(i.claims, &c); err != nil {
+		return nil, fmt.Errorf("oidc: failed to unmarshal standard claims: %v", err)
+	}
+	return &c, nil
+}
+
+// ErrNonceMismatch is returned by VerifyNonce when the token's nonce claim
+// doesn't match the one the caller sent with the authentication request, or
+// is missing entirely.
+var ErrNonceMismatch = errors.New("oidc: id token nonce does not match expected value")
+
+// VerifyNonce compares i.Nonce against expected in constant time and returns
+// ErrNonceMismatch if they differ or if the token carries no nonce at all.
+// Package doc for Nonce explains that this package does not do this check on
+// its own; callers using the implicit or hybrid flow should call this after
+// Verify or VerifyVerbose to protect against replay of a stolen ID token.
+func (i *IDToken) VerifyNonce(expected string) error {
+	if i.Nonce == "" || subtle.ConstantTimeCompare([]byte(i.Nonce), []byte(expected)) != 1 {
+		return ErrNonceMismatch
+	}
+	return nil
+}
+
 type idToken struct {
-	Issuer   string   `json:"iss"`
-	Subject  string   `json:"sub"`
-	Audience audience `json:"aud"`
-	Expiry   jsonTime `json:"exp"`
-	IssuedAt jsonTime `json:"iat"`
-	Nonce    string   `json:"nonce"`
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        audience `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+	Expiry          jsonTime `json:"exp"`
+	IssuedAt        jsonTime `json:"iat"`
+	Nonce           string   `json:"nonce"`
+	Email           string   `json:"email"`
+	EmailVerified   bool     `json:"email_verified"`
+	ACR             string   `json:"acr"`
+	AMR             []string `json:"amr"`
+	HD              string   `json:"hd"`
 }
 
 type audience []string
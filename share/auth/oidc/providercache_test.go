@@ -0,0 +1,62 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestProviderCacheDiscoverCoalescesConcurrentRequests covers ProviderCache's
+// singleflight coalescing: a burst of concurrent Discover calls for the same
+// issuer, with no cache entry yet, must hit the provider's discovery endpoint
+// only once and all return the same result.
+func TestProviderCacheDiscoverCoalescesConcurrentRequests(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(20 * time.Millisecond) // widen the window for concurrent callers to overlap
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 "http://" + r.Host,
+			"authorization_endpoint": "http://" + r.Host + "/auth",
+			"token_endpoint":         "http://" + r.Host + "/token",
+			"jwks_uri":               "http://" + r.Host + "/jwks",
+		})
+	}))
+	defer srv.Close()
+	issuer := srv.URL
+
+	cache := &ProviderCache{}
+
+	const concurrency = 10
+	var wg sync.WaitGroup
+	results := make([]*Endpoints, concurrency)
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = cache.Discover(context.Background(), issuer)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Discover call %d failed: %v", i, err)
+		}
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("expected the discovery endpoint to be hit exactly once, got %d", got)
+	}
+	for i, r := range results {
+		if r.TokenURL != results[0].TokenURL {
+			t.Errorf("result %d TokenURL = %q, want %q", i, r.TokenURL, results[0].TokenURL)
+		}
+	}
+}
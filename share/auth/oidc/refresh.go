@@ -0,0 +1,55 @@
+package oidc
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultRefreshFraction is used by RefreshAt when the caller passes a
+// fraction outside (0, 1]. Refreshing at 80% of the remaining lifetime
+// leaves a comfortable margin for the refresh round trip to complete
+// before the token actually expires.
+const DefaultRefreshFraction = 0.8
+
+// TokenLifetime returns the remaining validity duration of token, i.e. how
+// long until it expires. It uses token.Expiry, which oauth2 populates from
+// the token response's expires_in, and falls back to idToken's exp claim
+// when the provider omitted expires_in. idToken may be nil if the response
+// carried no id_token.
+func TokenLifetime(token *oauth2.Token, idToken *IDToken) (time.Duration, error) {
+	expiry, err := effectiveExpiry(token, idToken)
+	if err != nil {
+		return 0, err
+	}
+	return time.Until(expiry), nil
+}
+
+// RefreshAt returns the recommended time to proactively refresh token: a
+// point fraction of the way through its remaining lifetime, so the managed
+// session can schedule a refresh that completes well before actual expiry.
+// fraction must be in (0, 1]; any other value falls back to
+// DefaultRefreshFraction.
+func RefreshAt(token *oauth2.Token, idToken *IDToken, fraction float64) (time.Time, error) {
+	if fraction <= 0 || fraction > 1 {
+		fraction = DefaultRefreshFraction
+	}
+	lifetime, err := TokenLifetime(token, idToken)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(time.Duration(float64(lifetime) * fraction)), nil
+}
+
+// effectiveExpiry returns token's expiry, falling back to idToken's exp
+// claim when the token response didn't carry expires_in.
+func effectiveExpiry(token *oauth2.Token, idToken *IDToken) (time.Time, error) {
+	if token != nil && !token.Expiry.IsZero() {
+		return token.Expiry, nil
+	}
+	if idToken != nil && !idToken.Expiry.IsZero() {
+		return idToken.Expiry, nil
+	}
+	return time.Time{}, errors.New("oidc: token response has no expires_in and no id_token exp claim to fall back on")
+}
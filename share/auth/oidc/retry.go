@@ -0,0 +1,157 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures retry-with-backoff behavior for Discover and
+// UserInfoReq against flaky identity providers. The zero value disables
+// retries (a single attempt), so existing callers that don't opt in via
+// RetryContext are unaffected.
+type RetryPolicy struct {
+	// MaxAttempts caps the total number of attempts, including the first.
+	// Values <= 1 mean no retry.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. Each subsequent
+	// retry doubles the previous delay, capped at MaxBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed backoff delay, ignored for a 429 response
+	// whose Retry-After header specifies a longer wait.
+	MaxBackoff time.Duration
+	// MaxElapsed caps the total wall-clock time spent retrying, across all
+	// attempts. Zero means no cap.
+	MaxElapsed time.Duration
+}
+
+type retryContextKey struct{}
+
+// RetryContext returns a new Context that carries policy, so Discover and
+// UserInfoReq retry transient failures -- 429s, 5xxs, and network errors --
+// against the request's endpoint instead of failing on the first error. A
+// 429 response's Retry-After header, when present, overrides the computed
+// backoff delay.
+//
+//	ctx := oidc.RetryContext(ctx, oidc.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second})
+//	endpoints, err := oidc.Discover(ctx, issuer)
+func RetryContext(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryContextKey{}, policy)
+}
+
+func retryPolicyFromContext(ctx context.Context) RetryPolicy {
+	if p, ok := ctx.Value(retryContextKey{}).(RetryPolicy); ok {
+		return p
+	}
+	return RetryPolicy{}
+}
+
+// isRetryableStatus reports whether statusCode is one doRequestWithRetry
+// should retry: 429 (rate limited) or any 5xx server error.
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// retryBackoff computes the delay before the given retry attempt (the 1-based
+// count of attempts made so far), doubling InitialBackoff each time and
+// capping at MaxBackoff. resp's Retry-After header, if present on a 429,
+// overrides the computed delay.
+func retryBackoff(policy RetryPolicy, attempt int, resp *http.Response) time.Duration {
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return d
+		}
+	}
+	backoff := policy.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+	}
+	if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	return backoff
+}
+
+// parseRetryAfter parses an HTTP Retry-After header value, which per RFC
+// 9110 is either a number of seconds or an HTTP date.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// doRequestWithRetry executes a request built by newReq via doRequest,
+// retrying on 429/5xx responses and network errors according to the
+// RetryPolicy carried in ctx via RetryContext. newReq is called once per
+// attempt, since a request can't be resent after its body has been read.
+// With no policy in ctx it makes exactly one attempt.
+//
+// On success, or once attempts are exhausted, it returns the request that
+// produced the last response along with that response and its fully-read
+// body, so callers can apply their own status handling -- and anything else
+// that needs the exact request/response pair, like Cache-Control parsing --
+// exactly as if retry weren't involved.
+func doRequestWithRetry(ctx context.Context, newReq func() (*http.Request, error)) (*http.Request, *http.Response, []byte, error) {
+	policy := retryPolicyFromContext(ctx)
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	start := time.Now()
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		resp, err := doRequest(ctx, req)
+		if err != nil {
+			lastErr = err
+		} else {
+			body, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			switch {
+			case readErr != nil:
+				lastErr = readErr
+			case !isRetryableStatus(resp.StatusCode):
+				return req, resp, body, nil
+			case attempt == maxAttempts:
+				return req, resp, body, nil
+			default:
+				lastErr = fmt.Errorf("%s: %s", resp.Status, body)
+			}
+		}
+
+		if attempt == maxAttempts {
+			break
+		}
+		wait := retryBackoff(policy, attempt, resp)
+		if policy.MaxElapsed > 0 && time.Since(start)+wait > policy.MaxElapsed {
+			break
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, nil, nil, ctx.Err()
+		}
+	}
+	return nil, nil, nil, lastErr
+}
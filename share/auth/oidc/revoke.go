@@ -0,0 +1,57 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// RevokeToken revokes token at revocationURL per RFC 7009. tokenTypeHint,
+// e.g. "access_token" or "refresh_token", tells the provider which token
+// endpoint it came from so it can find it faster; pass "" to omit it.
+// clientAuth supplies the client credentials used to authenticate to the
+// revocation endpoint, e.g. cfg.TokenSource(ctx, nil) for a
+// client_credentials-backed oauth2.Config, or a static token source
+// wrapping a pre-obtained client assertion.
+//
+// Per the spec, providers return HTTP 200 even when token was already
+// invalid or unknown, so a 200 response is always treated as success;
+// any other status is returned as an error with the response body for
+// diagnostics.
+func RevokeToken(ctx context.Context, revocationURL, token, tokenTypeHint string, clientAuth oauth2.TokenSource) error {
+	if revocationURL == "" {
+		return errors.New("oidc: revocation endpoint is not supported by this provider")
+	}
+
+	authToken, err := clientAuth.Token()
+	if err != nil {
+		return fmt.Errorf("oidc: get client credentials: %v", err)
+	}
+
+	form := url.Values{"token": {token}}
+	if tokenTypeHint != "" {
+		form.Set("token_type_hint", tokenTypeHint)
+	}
+
+	_, resp, body, err := doRequestWithRetry(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", revocationURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("oidc: create POST request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		authToken.SetAuthHeader(req)
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	return nil
+}
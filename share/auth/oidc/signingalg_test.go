@@ -0,0 +1,74 @@
+package oidc
+
+import (
+	"testing"
+
+	jose "github.com/go-jose/go-jose/v3"
+)
+
+// TestCheckSigningAlg covers the algorithm allow-list: the newly-supported
+// ES384/ES512/PS256 algorithms pass when listed, "none" is always rejected
+// regardless of SupportedSigningAlgs, and HS256 is only ever accepted once
+// Config.HMACSecret is set, closing the RS256-to-HS256 key-confusion attack.
+func TestCheckSigningAlg(t *testing.T) {
+	v := newTestVerifier(&Config{
+		SupportedSigningAlgs: []string{RS256, ES384, ES512, PS256, HS256},
+		HMACSecret:           []byte("shared-secret"),
+	})
+
+	for _, alg := range []string{RS256, ES384, ES512, PS256, HS256} {
+		if err := v.checkSigningAlg(alg); err != nil {
+			t.Errorf("checkSigningAlg(%q) = %v, want nil", alg, err)
+		}
+	}
+
+	if err := v.checkSigningAlg("none"); err == nil {
+		t.Error("expected the unsecured \"none\" algorithm to always be rejected")
+	}
+
+	if err := v.checkSigningAlg("ES256"); err == nil {
+		t.Error("expected an algorithm outside SupportedSigningAlgs to be rejected")
+	}
+
+	// Without HMACSecret configured, HS256 must be rejected even when it's
+	// listed in SupportedSigningAlgs -- never fall back to verifying an
+	// HS256 signature against the provider's RSA public key.
+	vNoSecret := newTestVerifier(&Config{SupportedSigningAlgs: []string{RS256, HS256}})
+	if err := vNoSecret.checkSigningAlg(HS256); err == nil {
+		t.Error("expected HS256 to be rejected when HMACSecret is not configured")
+	}
+}
+
+// TestVerifySignatureHS256 exercises verifySignature's HMAC path end to end:
+// a JWS signed with the configured secret verifies, and one signed with a
+// different secret -- simulating a forged or confused token -- is rejected.
+func TestVerifySignatureHS256(t *testing.T) {
+	secret := []byte("shared-secret")
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: secret}, nil)
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	signed, err := signer.Sign([]byte(`{"sub":"user1"}`))
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	raw, err := signed.CompactSerialize()
+	if err != nil {
+		t.Fatalf("CompactSerialize failed: %v", err)
+	}
+
+	jws, err := jose.ParseSigned(raw)
+	if err != nil {
+		t.Fatalf("ParseSigned failed: %v", err)
+	}
+
+	v := newTestVerifier(&Config{HMACSecret: secret})
+	if _, err := v.verifySignature(nil, jws, HS256); err != nil {
+		t.Errorf("expected verification with the correct secret to succeed, got %v", err)
+	}
+
+	vWrongSecret := newTestVerifier(&Config{HMACSecret: []byte("wrong-secret")})
+	if _, err := vWrongSecret.verifySignature(nil, jws, HS256); err == nil {
+		t.Error("expected verification with the wrong secret to fail")
+	}
+}
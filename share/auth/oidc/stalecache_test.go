@@ -0,0 +1,83 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestProviderCacheDiscoverServesStaleWithinMaxStaleness covers the
+// MaxStaleness fallback: once the cached entry has expired, a Discover call
+// made while the discovery endpoint is unreachable must still succeed with
+// the previously cached endpoints, as long as the entry expired no longer
+// ago than MaxStaleness.
+func TestProviderCacheDiscoverServesStaleWithinMaxStaleness(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 "http://" + r.Host,
+			"authorization_endpoint": "http://" + r.Host + "/auth",
+			"token_endpoint":         "http://" + r.Host + "/token",
+			"jwks_uri":               "http://" + r.Host + "/jwks",
+		})
+	}))
+	issuer := srv.URL
+
+	cache := &ProviderCache{
+		DefaultTTL:   10 * time.Millisecond,
+		MaxStaleness: time.Minute,
+	}
+
+	first, err := cache.Discover(context.Background(), issuer)
+	if err != nil {
+		t.Fatalf("initial Discover failed: %v", err)
+	}
+
+	// Let the cached entry expire, then take the discovery endpoint down to
+	// simulate an outage.
+	time.Sleep(20 * time.Millisecond)
+	srv.Close()
+
+	stale, err := cache.Discover(context.Background(), issuer)
+	if err != nil {
+		t.Fatalf("expected Discover to fall back to the stale cached entry, got error: %v", err)
+	}
+	if stale.TokenURL != first.TokenURL {
+		t.Errorf("stale TokenURL = %q, want %q", stale.TokenURL, first.TokenURL)
+	}
+}
+
+// TestProviderCacheDiscoverFailsPastMaxStaleness covers the other side of the
+// fallback: once an expired entry is older than MaxStaleness, Discover must
+// propagate the refresh error instead of serving it.
+func TestProviderCacheDiscoverFailsPastMaxStaleness(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 "http://" + r.Host,
+			"authorization_endpoint": "http://" + r.Host + "/auth",
+			"token_endpoint":         "http://" + r.Host + "/token",
+			"jwks_uri":               "http://" + r.Host + "/jwks",
+		})
+	}))
+	issuer := srv.URL
+
+	cache := &ProviderCache{
+		DefaultTTL:   10 * time.Millisecond,
+		MaxStaleness: 10 * time.Millisecond,
+	}
+
+	if _, err := cache.Discover(context.Background(), issuer); err != nil {
+		t.Fatalf("initial Discover failed: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	srv.Close()
+
+	if _, err := cache.Discover(context.Background(), issuer); err == nil {
+		t.Error("expected Discover to fail once the entry is older than MaxStaleness")
+	}
+}
@@ -3,11 +3,13 @@ package oidc
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	jose "github.com/go-jose/go-jose/v3"
@@ -19,6 +21,19 @@ const (
 	issuerGoogleAccountsNoScheme = "accounts.google.com"
 )
 
+// defaultNegativeCacheTTL bounds how long a failed Verify is remembered, so a
+// flood of identical invalid tokens short-circuits to the cached failure
+// instead of repeating full verification -- including a possible JWKS
+// refetch on an unrecognized kid -- on every presentation.
+const defaultNegativeCacheTTL = 10 * time.Second
+
+// negCacheEntry is a cached Verify failure, keyed by the sha256 of the raw
+// token so the token itself is never retained.
+type negCacheEntry struct {
+	err     error
+	expires time.Time
+}
+
 // keySet is an interface that lets us stub out verification policies for
 // testing. Outside of testing, it's always backed by a remoteKeySet.
 type keySet interface {
@@ -30,6 +45,10 @@ type IDTokenVerifier struct {
 	keySet keySet
 	config *Config
 	issuer string
+
+	negMu    sync.Mutex
+	negCache map[[sha256.Size]byte]negCacheEntry
+	negTTL   time.Duration
 }
 
 // Config is the configuration for an IDTokenVerifier.
@@ -40,20 +59,152 @@ type Config struct {
 	//
 	// If not provided, users must explicitly set SkipClientIDCheck.
 	ClientID string
+	// ExpectedAudiences extends checkAudience with additional acceptable
+	// client IDs beyond ClientID, so a single verifier instance can accept
+	// tokens issued for any of several first-party clients (e.g. web, CLI,
+	// service) that all hit the same endpoint -- Verify passes as soon as
+	// aud intersects {ClientID} union ExpectedAudiences. The azp
+	// requirement in checkAuthorizedParty for multi-audience tokens is
+	// checked against the same set.
+	ExpectedAudiences []string
 	// If specified, only this set of algorithms may be used to sign the JWT.
 	//
 	// Since many providers only support RS256, SupportedSigningAlgs defaults to this value.
 	SupportedSigningAlgs []string
 
-	// If true, no ClientID check performed. Must be true if ClientID field is empty.
+	// SkipClientIDCheck disables the aud check in checkAudience: Verify no
+	// longer requires the token's audience to contain ClientID. Must be true
+	// if ClientID and ExpectedAudiences are both empty.
+	//
+	// This is a deliberate, narrow escape hatch for token-relay scenarios --
+	// e.g. a gateway that receives an ID token minted for a downstream
+	// client and only needs to read its claims -- NOT a general-purpose
+	// relaxation. Signature, issuer and expiry are still verified regardless.
+	// Enabling this means ANY token from this issuer, minted for ANY
+	// audience, will verify successfully here; do not set it unless the
+	// caller enforces the correct audience itself downstream.
 	SkipClientIDCheck bool
 	// If true, token expiry is not checked.
 	SkipExpiryCheck bool
 
 	// Time function to check Token expiry. Defaults to time.Now
 	Now func() time.Time
+
+	// NegativeCacheTTL bounds how long Verify remembers a failed
+	// verification for a given raw token, so repeated presentations of the
+	// same invalid token short-circuit to the cached failure. Defaults to
+	// defaultNegativeCacheTTL when zero.
+	NegativeCacheTTL time.Duration
+
+	// RequireEmailVerified rejects a token that carries an email claim whose
+	// email_verified claim is false or absent. Tokens with no email claim at
+	// all are unaffected, since not every provider or scope set includes one.
+	RequireEmailVerified bool
+
+	// MaxTokenAge rejects tokens whose iat is older than this, guarding
+	// against a stale or replayed token being presented long after it was
+	// issued. Zero disables the check.
+	MaxTokenAge time.Duration
+
+	// ClockSkew is the tolerance applied to the iat check in both
+	// directions: it forgives an iat up to ClockSkew in the future (clock
+	// drift between this host and the IdP) and is added to MaxTokenAge when
+	// deciding whether a token is too old. Ignored unless MaxTokenAge is
+	// also set.
+	ClockSkew time.Duration
+
+	// RequiredACR, when set, rejects a token whose acr claim isn't one of the
+	// listed Authentication Context Class References -- e.g. requiring MFA
+	// was actually used for this login. Empty disables the check.
+	RequiredACR []string
+
+	// RequiredAMR, when set, rejects a token whose amr claim doesn't include
+	// every listed Authentication Method Reference, e.g. []string{"mfa"} to
+	// demand step-up authentication. Empty disables the check.
+	RequiredAMR []string
+
+	// AcceptableIssuers extends checkIssuer's exact-match against the
+	// configured issuer with an explicit allowlist of additional values that
+	// are also accepted, e.g. a provider's other known-good issuer variants
+	// (see the Google accounts.google.com quirk documented on IDToken.Issuer).
+	// Unlike SkipClientIDCheck-style escape hatches, this doesn't disable the
+	// issuer check -- only issuer or one of AcceptableIssuers validates.
+	AcceptableIssuers []string
+
+	// RequiredHostedDomain, when set, restricts login to a single Google
+	// Workspace domain: after signature/issuer/audience validation, the
+	// token's hd claim must equal this value. A missing hd claim -- as a
+	// personal gmail.com account's token always has -- is treated as a
+	// failure whenever this is set, since it's the only signal
+	// distinguishing a Workspace account from a personal one.
+	RequiredHostedDomain string
+
+	// HMACSecret, when set, lets Verify accept an ID token signed with
+	// HS256 using this shared secret (typically the client secret some
+	// internal providers sign with) via HMAC, instead of requiring a
+	// signature verifiable against v.keySet's JWKS. HS256 must also be
+	// listed in SupportedSigningAlgs. Every other algorithm is still
+	// verified against the JWKS as usual.
+	//
+	// Leave nil unless a specific provider requires it: accepting HS256 at
+	// all is what enables the classic RS256-to-HS256 key confusion attack,
+	// where a token forged with the provider's (public, by design) RSA key
+	// used as an HMAC secret would otherwise verify successfully. Requiring
+	// this to be explicitly set closes that gap for every verifier that
+	// doesn't ask for it.
+	HMACSecret []byte
+}
+
+// ACRNotSatisfiedError is returned by Verify and included in VerifyVerbose's
+// VerificationErrors when Config.RequiredACR is set and the token's acr
+// claim isn't in the list.
+type ACRNotSatisfiedError struct {
+	ACR      string
+	Required []string
+}
+
+func (e *ACRNotSatisfiedError) Error() string {
+	return fmt.Sprintf("oidc: token acr %q does not satisfy required acr values %q", e.ACR, e.Required)
+}
+
+// AMRNotSatisfiedError is returned by Verify and included in VerifyVerbose's
+// VerificationErrors when Config.RequiredAMR is set and the token's amr
+// claim is missing one or more of the required methods -- most commonly
+// "mfa", when a step-up auth policy demands it but the login didn't use it.
+type AMRNotSatisfiedError struct {
+	AMR      []string
+	Required []string
 }
 
+func (e *AMRNotSatisfiedError) Error() string {
+	for _, r := range e.Required {
+		if r == "mfa" && !contains(e.AMR, "mfa") {
+			return fmt.Sprintf("oidc: token requires MFA but amr %q does not include it", e.AMR)
+		}
+	}
+	return fmt.Sprintf("oidc: token amr %q does not satisfy required amr values %q", e.AMR, e.Required)
+}
+
+// HostedDomainNotSatisfiedError is returned by Verify and included in
+// VerifyVerbose's VerificationErrors when Config.RequiredHostedDomain is set
+// and the token's hd claim doesn't match, including when it's absent.
+type HostedDomainNotSatisfiedError struct {
+	HostedDomain string
+	Required     string
+}
+
+func (e *HostedDomainNotSatisfiedError) Error() string {
+	if e.HostedDomain == "" {
+		return fmt.Sprintf("oidc: token has no hd claim, required hosted domain %q", e.Required)
+	}
+	return fmt.Sprintf("oidc: token hd %q does not match required hosted domain %q", e.HostedDomain, e.Required)
+}
+
+// ErrEmailNotVerified is returned by Verify and included in VerifyVerbose's
+// VerificationErrors when Config.RequireEmailVerified is set and the token's
+// email claim is not verified.
+var ErrEmailNotVerified = errors.New("oidc: email claim is present but not verified")
+
 // Verifier returns an IDTokenVerifier that uses the provider's key set to verify JWTs.
 //
 // The returned IDTokenVerifier is tied to the Provider's context and its behavior is
@@ -63,17 +214,59 @@ func (p *Provider) Verifier(config *Config) *IDTokenVerifier {
 	return NewVerifier(p.remoteKeySet, config, p.Issuer)
 }
 
+// NewVerifierFromJWKSURL is a convenience constructor for callers that only
+// have a provider's jwks_uri (rather than a full Provider), e.g. when the
+// endpoints were obtained out-of-band. It builds the remoteKeySet used to
+// validate the RS256/ES256/... signature on Verify.
+func NewVerifierFromJWKSURL(ctx context.Context, jwksURL string, config *Config, issuer string) *IDTokenVerifier {
+	return NewVerifier(NewRemoteKeySet(ctx, jwksURL, config.Now), config, issuer)
+}
+
 func NewVerifier(keySet keySet, config *Config, issuer string) *IDTokenVerifier {
 	// If SupportedSigningAlgs is empty defaults to only support RS256.
 	if len(config.SupportedSigningAlgs) == 0 {
 		config.SupportedSigningAlgs = []string{RS256}
 	}
 
+	negTTL := config.NegativeCacheTTL
+	if negTTL <= 0 {
+		negTTL = defaultNegativeCacheTTL
+	}
+
 	return &IDTokenVerifier{
-		keySet: keySet,
-		config: config,
-		issuer: issuer,
+		keySet:   keySet,
+		config:   config,
+		issuer:   issuer,
+		negCache: make(map[[sha256.Size]byte]negCacheEntry),
+		negTTL:   negTTL,
+	}
+}
+
+// cachedFailure returns the cached Verify error for key, if present and not
+// yet expired.
+func (v *IDTokenVerifier) cachedFailure(key [sha256.Size]byte) (error, bool) {
+	v.negMu.Lock()
+	defer v.negMu.Unlock()
+	entry, ok := v.negCache[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.err, true
+}
+
+// cacheFailure remembers a failed Verify for negTTL. It also opportunistically
+// evicts other expired entries so the cache doesn't grow unbounded under a
+// sustained flood of distinct invalid tokens.
+func (v *IDTokenVerifier) cacheFailure(key [sha256.Size]byte, err error) {
+	v.negMu.Lock()
+	defer v.negMu.Unlock()
+	now := time.Now()
+	for k, e := range v.negCache {
+		if now.After(e.expires) {
+			delete(v.negCache, k)
+		}
 	}
+	v.negCache[key] = negCacheEntry{err: err, expires: now.Add(v.negTTL)}
 }
 
 func parseJWT(p string) ([]byte, error) {
@@ -97,6 +290,50 @@ func contains(sli []string, ele string) bool {
 	return false
 }
 
+// UnsupportedSigningAlgError is returned by Verify and VerifyVerbose when an
+// ID token's JWS header names an algorithm outside the verifier's
+// SupportedSigningAlgs, or the unsecured "none" algorithm, which is always
+// rejected regardless of SupportedSigningAlgs.
+type UnsupportedSigningAlgError struct {
+	Algorithm string
+	Allowed   []string
+}
+
+func (e *UnsupportedSigningAlgError) Error() string {
+	return fmt.Sprintf("oidc: id token signed with unsupported algorithm, expected %q got %q", e.Allowed, e.Algorithm)
+}
+
+// checkSigningAlg rejects the unsecured "none" algorithm outright, then
+// enforces v.config.SupportedSigningAlgs (ES384, ES512 and PS256 are
+// supported when listed there; go-jose verifies them the same as any other
+// algorithm in v.keySet.verify).
+func (v *IDTokenVerifier) checkSigningAlg(alg string) error {
+	if alg == "none" {
+		return &UnsupportedSigningAlgError{Algorithm: alg, Allowed: v.config.SupportedSigningAlgs}
+	}
+	if alg == HS256 && len(v.config.HMACSecret) == 0 {
+		// Never fall back to keySet.verify for HS256 -- that would let a
+		// token forged with the provider's RSA public key as an HMAC secret
+		// verify successfully. See Config.HMACSecret.
+		return &UnsupportedSigningAlgError{Algorithm: alg, Allowed: v.config.SupportedSigningAlgs}
+	}
+	if len(v.config.SupportedSigningAlgs) != 0 && !contains(v.config.SupportedSigningAlgs, alg) {
+		return &UnsupportedSigningAlgError{Algorithm: alg, Allowed: v.config.SupportedSigningAlgs}
+	}
+	return nil
+}
+
+// verifySignature dispatches to HMAC verification against Config.HMACSecret
+// for an HS256-signed jws, or v.keySet's JWKS-backed verification for every
+// other algorithm. Callers must have already run checkSigningAlg, which
+// rejects HS256 outright unless HMACSecret is configured.
+func (v *IDTokenVerifier) verifySignature(ctx context.Context, jws *jose.JSONWebSignature, alg string) ([]byte, error) {
+	if alg == HS256 {
+		return jws.Verify(v.config.HMACSecret)
+	}
+	return v.keySet.verify(ctx, jws)
+}
+
 // Verify parses a raw ID Token, verifies it's been signed by the provider, preforms
 // any additional checks depending on the Config, and returns the payload.
 //
@@ -117,6 +354,21 @@ func contains(sli []string, ele string) bool {
 //
 //	token, err := verifier.Verify(ctx, rawIDToken)
 func (v *IDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*IDToken, error) {
+	key := sha256.Sum256([]byte(rawIDToken))
+	if err, ok := v.cachedFailure(key); ok {
+		return nil, err
+	}
+
+	t, err := v.verifyToken(ctx, rawIDToken)
+	if err != nil {
+		v.cacheFailure(key, err)
+	}
+	return t, err
+}
+
+// verifyToken does the actual work for Verify; see Verify for the
+// negative-cache wrapper around it.
+func (v *IDTokenVerifier) verifyToken(ctx context.Context, rawIDToken string) (*IDToken, error) {
 	jws, err := jose.ParseSigned(rawIDToken)
 	if err != nil {
 		return nil, fmt.Errorf("oidc: malformed jwt: %v", err)
@@ -134,53 +386,45 @@ func (v *IDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*IDTok
 	}
 
 	t := &IDToken{
-		Issuer:   token.Issuer,
-		Subject:  token.Subject,
-		Audience: []string(token.Audience),
-		Expiry:   time.Time(token.Expiry),
-		IssuedAt: time.Time(token.IssuedAt),
-		Nonce:    token.Nonce,
-		claims:   payload,
-	}
-
-	// Check issuer.
-	if t.Issuer != v.issuer {
-		// Google sometimes returns "accounts.google.com" as the issuer claim instead of
-		// the required "https://accounts.google.com". Detect this case and allow it only
-		// for Google.
-		//
-		// We will not add hooks to let other providers go off spec like this.
-		if v.issuer == issuerGoogleAccounts && t.Issuer == issuerGoogleAccountsNoScheme {
-		} else if q := strings.LastIndex(v.issuer, "?"); q != -1 && t.Issuer == v.issuer[:q] {
-			// NVSHAS-4739: remove query, https://test.iam.cloud.ibm.com/identity?account={ACCOUNTID}
-		} else {
-			return nil, fmt.Errorf("oidc: id token issued by a different provider, expected %q got %q", v.issuer, t.Issuer)
-		}
+		Issuer:          token.Issuer,
+		Subject:         token.Subject,
+		Audience:        []string(token.Audience),
+		AuthorizedParty: token.AuthorizedParty,
+		Expiry:          time.Time(token.Expiry),
+		IssuedAt:        time.Time(token.IssuedAt),
+		Nonce:           token.Nonce,
+		ACR:             token.ACR,
+		AuthMethods:     token.AMR,
+		HostedDomain:    token.HD,
+		claims:          payload,
 	}
 
-	// If a client ID has been provided, make sure it's part of the audience. SkipClientIDCheck must be true if ClientID is empty.
-	//
-	// This check DOES NOT ensure that the ClientID is the party to which the ID Token was issued (i.e. Authorized party).
-	if !v.config.SkipClientIDCheck {
-		if v.config.ClientID != "" {
-			if !contains(t.Audience, v.config.ClientID) {
-				return nil, fmt.Errorf("oidc: expected audience %q got %q", v.config.ClientID, t.Audience)
-			}
-		} else {
-			return nil, fmt.Errorf("oidc: Invalid configuration. ClientID must be provided or SkipClientIDCheck must be set.")
-		}
+	if err := v.checkIssuer(t.Issuer); err != nil {
+		return nil, err
 	}
-
-	// If a SkipExpiryCheck is false, make sure token is not expired.
-	if !v.config.SkipExpiryCheck {
-		now := time.Now
-		if v.config.Now != nil {
-			now = v.config.Now
-		}
-
-		if t.Expiry.Before(now()) {
-			return nil, fmt.Errorf("oidc: token is expired (Token Expiry: %v)", t.Expiry)
-		}
+	if err := v.checkAudience(t.Audience); err != nil {
+		return nil, err
+	}
+	if err := v.checkAuthorizedParty(t.Audience, t.AuthorizedParty); err != nil {
+		return nil, err
+	}
+	if err := v.checkExpiry(t.Expiry); err != nil {
+		return nil, err
+	}
+	if err := v.checkIssuedAt(t.IssuedAt); err != nil {
+		return nil, err
+	}
+	if err := v.checkACR(t.ACR); err != nil {
+		return nil, err
+	}
+	if err := v.checkAMR(t.AuthMethods); err != nil {
+		return nil, err
+	}
+	if err := v.checkHostedDomain(t.HostedDomain); err != nil {
+		return nil, err
+	}
+	if err := v.checkEmailVerified(token.Email, token.EmailVerified); err != nil {
+		return nil, err
 	}
 
 	switch len(jws.Signatures) {
@@ -192,11 +436,11 @@ func (v *IDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*IDTok
 	}
 
 	sig := jws.Signatures[0]
-	if len(v.config.SupportedSigningAlgs) != 0 && !contains(v.config.SupportedSigningAlgs, sig.Header.Algorithm) {
-		return nil, fmt.Errorf("oidc: id token signed with unsupported algorithm, expected %q got %q", v.config.SupportedSigningAlgs, sig.Header.Algorithm)
+	if err := v.checkSigningAlg(sig.Header.Algorithm); err != nil {
+		return nil, err
 	}
 
-	gotPayload, err := v.keySet.verify(ctx, jws)
+	gotPayload, err := v.verifySignature(ctx, jws, sig.Header.Algorithm)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify signature: %v", err)
 	}
@@ -209,6 +453,269 @@ func (v *IDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*IDTok
 	return t, nil
 }
 
+// checkIssuer validates the token's iss claim against the configured issuer.
+func (v *IDTokenVerifier) checkIssuer(issuer string) error {
+	if issuer == v.issuer {
+		return nil
+	}
+	// Google sometimes returns "accounts.google.com" as the issuer claim instead of
+	// the required "https://accounts.google.com". Detect this case and allow it only
+	// for Google.
+	//
+	// We will not add hooks to let other providers go off spec like this.
+	if v.issuer == issuerGoogleAccounts && issuer == issuerGoogleAccountsNoScheme {
+		return nil
+	}
+	if q := strings.LastIndex(v.issuer, "?"); q != -1 && issuer == v.issuer[:q] {
+		// NVSHAS-4739: remove query, https://test.iam.cloud.ibm.com/identity?account={ACCOUNTID}
+		return nil
+	}
+	if contains(v.config.AcceptableIssuers, issuer) {
+		return nil
+	}
+	return fmt.Errorf("oidc: id token issued by a different provider, expected %q got %q", v.issuer, issuer)
+}
+
+// acceptedClientIDs returns every client ID checkAudience/checkAuthorizedParty
+// treat as valid: ClientID plus ExpectedAudiences, so callers that only set
+// ClientID see no behavior change.
+func (v *IDTokenVerifier) acceptedClientIDs() []string {
+	if v.config.ClientID == "" {
+		return v.config.ExpectedAudiences
+	}
+	return append([]string{v.config.ClientID}, v.config.ExpectedAudiences...)
+}
+
+// checkAudience validates aud against the configured ClientID and
+// ExpectedAudiences, passing as soon as any one of them appears in
+// audience. SkipClientIDCheck must be true if ClientID and ExpectedAudiences
+// are both empty.
+//
+// This check DOES NOT ensure that the accepted client ID is the party to which the ID Token was issued (i.e. Authorized party).
+func (v *IDTokenVerifier) checkAudience(audience []string) error {
+	if v.config.SkipClientIDCheck {
+		return nil
+	}
+	accepted := v.acceptedClientIDs()
+	if len(accepted) == 0 {
+		return fmt.Errorf("oidc: Invalid configuration. ClientID must be provided or SkipClientIDCheck must be set.")
+	}
+	for _, id := range accepted {
+		if contains(audience, id) {
+			return nil
+		}
+	}
+	return fmt.Errorf("oidc: expected audience to contain one of %q got %q", accepted, audience)
+}
+
+// checkAuthorizedParty enforces the OIDC requirement that when a token is
+// issued for more than one audience, it must carry an azp claim identifying
+// which of them it was actually issued to, and that azp match one of the
+// configured ClientID/ExpectedAudiences. This closes the token-confusion gap
+// where a multi-audience token obtained for one client could otherwise be
+// replayed against another.
+func (v *IDTokenVerifier) checkAuthorizedParty(audience []string, azp string) error {
+	if v.config.SkipClientIDCheck || len(audience) <= 1 {
+		return nil
+	}
+	if azp == "" {
+		return errors.New("oidc: id token has multiple audiences but no azp claim")
+	}
+	accepted := v.acceptedClientIDs()
+	if !contains(accepted, azp) {
+		return fmt.Errorf("oidc: id token azp %q does not match any accepted client ID %q", azp, accepted)
+	}
+	return nil
+}
+
+// checkExpiry rejects tokens that have expired, unless SkipExpiryCheck is set.
+func (v *IDTokenVerifier) checkExpiry(expiry time.Time) error {
+	if v.config.SkipExpiryCheck {
+		return nil
+	}
+	now := time.Now
+	if v.config.Now != nil {
+		now = v.config.Now
+	}
+	if expiry.Before(now()) {
+		return fmt.Errorf("oidc: token is expired (Token Expiry: %v)", expiry)
+	}
+	return nil
+}
+
+// checkIssuedAt enforces Config.MaxTokenAge, rejecting a token whose iat is
+// more than ClockSkew in the future (clock drift or forgery) or older than
+// MaxTokenAge+ClockSkew (stale). Disabled unless MaxTokenAge is set.
+func (v *IDTokenVerifier) checkIssuedAt(issuedAt time.Time) error {
+	if v.config.MaxTokenAge <= 0 {
+		return nil
+	}
+	now := time.Now
+	if v.config.Now != nil {
+		now = v.config.Now
+	}
+	skew := v.config.ClockSkew
+	t := now()
+	if issuedAt.After(t.Add(skew)) {
+		return fmt.Errorf("oidc: token iat is in the future (iat: %v)", issuedAt)
+	}
+	if issuedAt.Before(t.Add(-(v.config.MaxTokenAge + skew))) {
+		return fmt.Errorf("oidc: token is too old (iat: %v, MaxTokenAge: %v)", issuedAt, v.config.MaxTokenAge)
+	}
+	return nil
+}
+
+// checkACR enforces Config.RequiredACR, disabled unless it's set.
+func (v *IDTokenVerifier) checkACR(acr string) error {
+	if len(v.config.RequiredACR) == 0 {
+		return nil
+	}
+	if !contains(v.config.RequiredACR, acr) {
+		return &ACRNotSatisfiedError{ACR: acr, Required: v.config.RequiredACR}
+	}
+	return nil
+}
+
+// checkAMR enforces Config.RequiredAMR, disabled unless it's set. The token
+// must carry every required method, not merely one of them.
+func (v *IDTokenVerifier) checkAMR(amr []string) error {
+	if len(v.config.RequiredAMR) == 0 {
+		return nil
+	}
+	for _, required := range v.config.RequiredAMR {
+		if !contains(amr, required) {
+			return &AMRNotSatisfiedError{AMR: amr, Required: v.config.RequiredAMR}
+		}
+	}
+	return nil
+}
+
+// checkHostedDomain enforces Config.RequiredHostedDomain, disabled unless
+// it's set. A missing hd claim never satisfies a configured requirement.
+func (v *IDTokenVerifier) checkHostedDomain(hd string) error {
+	if v.config.RequiredHostedDomain == "" {
+		return nil
+	}
+	if hd != v.config.RequiredHostedDomain {
+		return &HostedDomainNotSatisfiedError{HostedDomain: hd, Required: v.config.RequiredHostedDomain}
+	}
+	return nil
+}
+
+// checkEmailVerified enforces Config.RequireEmailVerified. A token without an
+// email claim at all passes, since RequireEmailVerified only guards against a
+// present-but-unverified email, not the absence of one.
+func (v *IDTokenVerifier) checkEmailVerified(email string, verified bool) error {
+	if !v.config.RequireEmailVerified {
+		return nil
+	}
+	if email != "" && !verified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// VerificationErrors aggregates every check that failed during VerifyVerbose,
+// so a misconfigured IdP can be fixed in a single pass instead of iterating on
+// one fast-fail error at a time.
+type VerificationErrors struct {
+	Errs []error
+}
+
+func (e *VerificationErrors) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("oidc: %d verification checks failed: %s", len(e.Errs), strings.Join(msgs, "; "))
+}
+
+// VerifyVerbose behaves like Verify but, instead of stopping at the first
+// failing check, runs every independent check (signature, issuer, audience,
+// expiry) and returns all failures together as a *VerificationErrors. The
+// partially-decoded IDToken is returned alongside the error so callers can
+// still inspect claims that did parse.
+func (v *IDTokenVerifier) VerifyVerbose(ctx context.Context, rawIDToken string) (*IDToken, error) {
+	jws, err := jose.ParseSigned(rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: malformed jwt: %v", err)
+	}
+
+	payload, err := parseJWT(rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: malformed jwt: %v", err)
+	}
+	var token idToken
+	if err := json.Unmarshal(payload, &token); err != nil {
+		return nil, fmt.Errorf("oidc: failed to unmarshal claims: %v", err)
+	}
+
+	t := &IDToken{
+		Issuer:          token.Issuer,
+		Subject:         token.Subject,
+		Audience:        []string(token.Audience),
+		AuthorizedParty: token.AuthorizedParty,
+		Expiry:          time.Time(token.Expiry),
+		IssuedAt:        time.Time(token.IssuedAt),
+		Nonce:           token.Nonce,
+		ACR:             token.ACR,
+		AuthMethods:     token.AMR,
+		HostedDomain:    token.HD,
+		claims:          payload,
+	}
+
+	var errs []error
+	if err := v.checkIssuer(t.Issuer); err != nil {
+		errs = append(errs, err)
+	}
+	if err := v.checkAudience(t.Audience); err != nil {
+		errs = append(errs, err)
+	}
+	if err := v.checkAuthorizedParty(t.Audience, t.AuthorizedParty); err != nil {
+		errs = append(errs, err)
+	}
+	if err := v.checkExpiry(t.Expiry); err != nil {
+		errs = append(errs, err)
+	}
+	if err := v.checkIssuedAt(t.IssuedAt); err != nil {
+		errs = append(errs, err)
+	}
+	if err := v.checkACR(t.ACR); err != nil {
+		errs = append(errs, err)
+	}
+	if err := v.checkAMR(t.AuthMethods); err != nil {
+		errs = append(errs, err)
+	}
+	if err := v.checkHostedDomain(t.HostedDomain); err != nil {
+		errs = append(errs, err)
+	}
+	if err := v.checkEmailVerified(token.Email, token.EmailVerified); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch len(jws.Signatures) {
+	case 0:
+		errs = append(errs, fmt.Errorf("oidc: id token not signed"))
+	case 1:
+		sig := jws.Signatures[0]
+		if err := v.checkSigningAlg(sig.Header.Algorithm); err != nil {
+			errs = append(errs, err)
+		}
+		if gotPayload, err := v.verifySignature(ctx, jws, sig.Header.Algorithm); err != nil {
+			errs = append(errs, fmt.Errorf("failed to verify signature: %v", err))
+		} else if !bytes.Equal(gotPayload, payload) {
+			errs = append(errs, errors.New("oidc: internal error, payload parsed did not match previous payload"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("oidc: multiple signatures on id token not supported"))
+	}
+
+	if len(errs) > 0 {
+		return t, &VerificationErrors{Errs: errs}
+	}
+	return t, nil
+}
+
 // Nonce returns an auth code option which requires the ID Token created by the
 // OpenID Connect provider to contain the specified nonce.
 func Nonce(nonce string) oauth2.AuthCodeOption {
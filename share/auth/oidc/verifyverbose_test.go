@@ -0,0 +1,60 @@
+package oidc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+)
+
+// TestVerifyVerboseReportsAllFailures covers VerifyVerbose's aggregation
+// behavior: a token failing several independent checks at once -- wrong
+// issuer, wrong audience, and expired -- must have every failure reported
+// together in a single *VerificationErrors, instead of Verify's stop-at-the-
+// first-error behavior.
+func TestVerifyVerboseReportsAllFailures(t *testing.T) {
+	secret := []byte("shared-secret")
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": "https://wrong-issuer.example.com",
+		"aud": "wrong-client",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+		"iat": time.Now().Add(-2 * time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("marshal claims failed: %v", err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: secret}, nil)
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	signed, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	rawIDToken, err := signed.CompactSerialize()
+	if err != nil {
+		t.Fatalf("CompactSerialize failed: %v", err)
+	}
+
+	v := &IDTokenVerifier{
+		issuer: "https://good-issuer.example.com",
+		config: &Config{
+			ClientID:             "client1",
+			HMACSecret:           secret,
+			SupportedSigningAlgs: []string{HS256},
+		},
+	}
+
+	_, err = v.VerifyVerbose(nil, rawIDToken)
+	if err == nil {
+		t.Fatal("expected VerifyVerbose to report failures")
+	}
+	verrs, ok := err.(*VerificationErrors)
+	if !ok {
+		t.Fatalf("expected a *VerificationErrors, got %T: %v", err, err)
+	}
+	if len(verrs.Errs) < 3 {
+		t.Errorf("expected issuer, audience, and expiry to all be reported, got %d errors: %v", len(verrs.Errs), verrs.Errs)
+	}
+}
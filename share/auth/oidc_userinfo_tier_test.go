@@ -0,0 +1,100 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/neuvector/neuvector/controller/api"
+	"github.com/neuvector/neuvector/share"
+	"github.com/neuvector/neuvector/share/auth"
+	"github.com/neuvector/neuvector/share/utils"
+)
+
+// signIDToken signs claims as a compact RS256 JWS with key, tagged with kid
+// so the verifying side's JWKS lookup can match it.
+func signIDToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, &jose.SignerOptions{
+		ExtraHeaders: map[jose.HeaderKey]interface{}{"kid": kid},
+	})
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims failed: %v", err)
+	}
+	signed, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	raw, err := signed.CompactSerialize()
+	if err != nil {
+		t.Fatalf("CompactSerialize failed: %v", err)
+	}
+	return raw
+}
+
+// TestOIDCAuthWithoutUserInfoEndpoint covers the "treat missing userinfo
+// endpoint as an optional tier" behavior in remoteAuth.OIDCAuth: a provider
+// with no UserInfoURL configured must still resolve claims -- including
+// groups -- from the id_token alone, instead of failing outright.
+func TestOIDCAuthWithoutUserInfoEndpoint(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	const kid = "test-key"
+	const issuer = "https://idp.example.com"
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwk := jose.JSONWebKey{Key: &key.PublicKey, KeyID: kid, Algorithm: "RS256", Use: "sig"}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{jwk}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		idToken := signIDToken(t, key, kid, map[string]interface{}{
+			"iss":    issuer,
+			"aud":    "client1",
+			"sub":    "user1",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"iat":    time.Now().Unix(),
+			"groups": []string{"engineering"},
+		})
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token1",
+			"token_type":   "Bearer",
+			"id_token":     idToken,
+		})
+	})
+
+	coidc := &share.CLUSServerOIDC{
+		Issuer:   issuer,
+		TokenURL: srv.URL + "/token",
+		JWKSURL:  srv.URL + "/jwks",
+		ClientID: "client1",
+		// UserInfoURL intentionally left empty: the provider under test has
+		// no userinfo endpoint.
+	}
+	state := utils.EncryptURLSafe(fmt.Sprintf("%d", time.Now().Unix()))
+	tokenData := &api.RESTAuthToken{Token: "auth-code1", State: state}
+
+	auther := auth.NewRemoteAuther(nil)
+	claims, err := auther.OIDCAuth(coidc, tokenData)
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", claims["sub"])
+	assert.ElementsMatch(t, []interface{}{"engineering"}, claims["groups"])
+}
@@ -2067,6 +2067,21 @@ type CLUSFileMonitorFilter struct {
 	CustomerAdd  bool   `json:"customer_add"`
 	Behavior     string `json:"behavior"`
 	DerivedGroup string `json:"dgroup,omitempty"`
+	// EUIDs, when non-empty, restricts reported events to processes whose effective
+	// UID is in the set. This lets a filter narrow to, e.g., non-root or a service
+	// account UID inside the container.
+	EUIDs []int `json:"euids,omitempty"`
+	// CaptureOpenFDs enables forensic enrichment of matching events: a bounded
+	// snapshot of the modifying process's currently open file paths is
+	// attached to the report. Off by default due to the extra syscalls per
+	// event and the privacy implications of listing a process's open files.
+	CaptureOpenFDs bool `json:"capture_open_fds,omitempty"`
+	// MaxDepth, when set on a Recursive filter, caps how many directory
+	// levels below Path are walked and watched -- 1 means only Path's direct
+	// children, 0 means unlimited (the historical behavior). Guards against
+	// the watch count exploding under a broad recursive filter like /usr/bin
+	// as new subdirectories appear.
+	MaxDepth int `json:"max_depth,omitempty"`
 }
 
 type CLUSFileMonitorProfile struct {
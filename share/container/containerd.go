@@ -406,6 +406,10 @@ func (d *containerdDriver) GetContainer(id string) (*ContainerMetaExtra, error)
 		Networks:      utils.NewSet(),
 	}
 
+	for _, m := range spec.Mounts {
+		meta.Mounts = append(meta.Mounts, ContainerMetaMount{Source: m.Source, Destination: m.Destination})
+	}
+
 	if !info.CreatedAt.IsZero() {
 		meta.CreatedAt = info.CreatedAt
 		meta.StartedAt = meta.CreatedAt
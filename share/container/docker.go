@@ -242,6 +242,10 @@ func (d *dockerDriver) GetContainer(id string) (*ContainerMetaExtra, error) {
 		LogPath:     info.LogPath,
 	}
 
+	for _, m := range info.Mounts {
+		meta.Mounts = append(meta.Mounts, ContainerMetaMount{Source: m.Source, Destination: m.Destination})
+	}
+
 	if info.Config != nil && info.Config.Healthcheck != nil {
 		// log.WithFields(log.Fields{"health": info.Config.Healthcheck}).Debug()
 		meta.Healthcheck = make([]string, len(info.Config.Healthcheck.Test))
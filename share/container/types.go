@@ -162,6 +162,14 @@ type ContainerMetaExtra struct {
 	Networks    utils.Set
 	LogPath     string
 	Healthcheck []string
+	Mounts      []ContainerMetaMount
+}
+
+// ContainerMetaMount describes a single bind mount into the container, as
+// reported by the runtime.
+type ContainerMetaMount struct {
+	Source      string
+	Destination string
 }
 
 func ConnectDocker(endpoint string, sys *system.SystemTools) (Runtime, error) {
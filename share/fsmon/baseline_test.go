@@ -0,0 +1,51 @@
+package fsmon
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestExportImportBaselineRoundTrip exports a group's baseline and re-imports
+// it, e.g. to compare it against a live container's current baseline in a
+// test, per ImportBaseline's own doc comment.
+func TestExportImportBaselineRoundTrip(t *testing.T) {
+	const rootPid = 4242
+	w := &FileWatch{
+		bEnable: true,
+		groups:  map[int]*groupInfo{rootPid: {cid: "container1"}},
+	}
+
+	data, err := w.ExportBaseline(rootPid)
+	if err != nil {
+		t.Fatalf("ExportBaseline failed: %v", err)
+	}
+
+	baseline, err := ImportBaseline(data)
+	if err != nil {
+		t.Fatalf("ImportBaseline failed on a freshly exported baseline: %v", err)
+	}
+	if baseline.RootPid != rootPid {
+		t.Errorf("expected RootPid %d, got %d", rootPid, baseline.RootPid)
+	}
+	if baseline.Version != fileBaselineVersion {
+		t.Errorf("expected Version %d, got %d", fileBaselineVersion, baseline.Version)
+	}
+}
+
+// TestImportBaselineRejectsVersionMismatch exercises ImportBaseline's only
+// validation: it must reject a document from a bumped or garbage Version
+// rather than silently misreading it.
+func TestImportBaselineRejectsVersionMismatch(t *testing.T) {
+	bumped := ExportedBaseline{Version: fileBaselineVersion + 1, RootPid: 1}
+	data, err := json.Marshal(&bumped)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if _, err := ImportBaseline(data); err == nil {
+		t.Error("expected ImportBaseline to reject a document with a newer Version")
+	}
+
+	if _, err := ImportBaseline([]byte("not json")); err == nil {
+		t.Error("expected ImportBaseline to reject garbage input")
+	}
+}
@@ -0,0 +1,44 @@
+package fsmon
+
+import (
+	"testing"
+
+	"github.com/neuvector/neuvector/share"
+)
+
+// TestReportBatchesNormalFlushesDeny covers report's dispatch when
+// SendReportBatch is configured: a normal violate event is queued for
+// batched delivery instead of being sent immediately, while a deny event
+// bypasses the batch and is delivered right away.
+func TestReportBatchesNormalFlushesDeny(t *testing.T) {
+	var immediate []*MonitorMessage
+	var batched [][]*MonitorMessage
+	w := &FileWatch{
+		sendrpt:      func(m *MonitorMessage) bool { immediate = append(immediate, m); return true },
+		sendBatch:    func(msgs []*MonitorMessage) bool { batched = append(batched, msgs); return true },
+		batchMaxSize: 10,
+	}
+
+	normal := &MonitorMessage{Path: "/tmp/a", Action: share.PolicyActionViolate}
+	w.report(normal)
+
+	if len(immediate) != 0 {
+		t.Errorf("expected a normal event to not be sent immediately, got %d immediate sends", len(immediate))
+	}
+	if len(w.batchBuf) != 1 || w.batchBuf[0] != normal {
+		t.Errorf("expected the normal event to be queued in the batch, got %v", w.batchBuf)
+	}
+
+	deny := &MonitorMessage{Path: "/tmp/b", Action: share.PolicyActionDeny}
+	w.report(deny)
+
+	if len(immediate) != 1 || immediate[0] != deny {
+		t.Errorf("expected the deny event to flush immediately via sendrpt, got %v", immediate)
+	}
+	if len(w.batchBuf) != 1 {
+		t.Errorf("expected the deny event to bypass the batch, batch still has %d entries", len(w.batchBuf))
+	}
+	if len(batched) != 0 {
+		t.Errorf("expected flushBatch to not have been called yet, got %d flushes", len(batched))
+	}
+}
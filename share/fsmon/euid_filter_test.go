@@ -0,0 +1,53 @@
+package fsmon
+
+import (
+	"testing"
+
+	"github.com/neuvector/neuvector/share"
+)
+
+// TestFilterProcInfoByEUID exercises a filter carrying an EUIDs restriction
+// against two processes with different effective UIDs touching the same
+// watched file, asserting only the configured UID's events survive.
+func TestFilterProcInfoByEUID(t *testing.T) {
+	grp := &groupInfo{
+		profile: &share.CLUSFileMonitorProfile{
+			Filters: []share.CLUSFileMonitorFilter{
+				{Path: "/etc/passwd", EUIDs: []int{1000}},
+			},
+		},
+	}
+
+	pInfo := []*ProcInfo{
+		{Path: "/bin/root-proc", EUid: 0},
+		{Path: "/bin/svc-proc", EUid: 1000},
+	}
+
+	filtered := filterProcInfoByEUID(grp, "/etc/passwd", pInfo)
+	if len(filtered) != 1 {
+		t.Fatalf("expected 1 process to survive the EUIDs filter, got %d", len(filtered))
+	}
+	if filtered[0].EUid != 1000 {
+		t.Errorf("expected the surviving process to have EUid 1000, got %d", filtered[0].EUid)
+	}
+
+	// A path with no matching filter, or a filter with no EUIDs set, imposes
+	// no restriction.
+	if unfiltered := filterProcInfoByEUID(grp, "/etc/shadow", pInfo); len(unfiltered) != 2 {
+		t.Errorf("expected no filtering for an unmatched path, got %d entries", len(unfiltered))
+	}
+}
+
+func TestFilterEUIDMatch(t *testing.T) {
+	flt := share.CLUSFileMonitorFilter{EUIDs: []int{1000, 1001}}
+
+	if !filterEUIDMatch(flt, &ProcInfo{EUid: 1001}) {
+		t.Error("expected EUid 1001 to match")
+	}
+	if filterEUIDMatch(flt, &ProcInfo{EUid: 0}) {
+		t.Error("expected EUid 0 to not match")
+	}
+	if !filterEUIDMatch(share.CLUSFileMonitorFilter{}, &ProcInfo{EUid: 0}) {
+		t.Error("expected an empty EUIDs list to place no restriction")
+	}
+}
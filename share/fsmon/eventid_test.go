@@ -0,0 +1,38 @@
+package fsmon
+
+import (
+	"testing"
+	"time"
+)
+
+// TestComputeEventIDStability covers computeEventID's content-addressing:
+// identical events within the same eventIDBucket window produce identical
+// IDs, while any distinct field -- container, path, event type, or process
+// path -- produces a different one.
+func TestComputeEventIDStability(t *testing.T) {
+	w := &FileWatch{eventIDBucket: time.Minute}
+
+	id1 := w.computeEventID("container1", "/tmp/a", fileEventModified, "")
+	id2 := w.computeEventID("container1", "/tmp/a", fileEventModified, "")
+	if id1 != id2 {
+		t.Errorf("expected identical events to produce identical IDs, got %q and %q", id1, id2)
+	}
+
+	cases := []struct {
+		name                string
+		cid, path, procPath string
+		event               uint32
+	}{
+		{"different container", "container2", "/tmp/a", "", fileEventModified},
+		{"different path", "container1", "/tmp/b", "", fileEventModified},
+		{"different event type", "container1", "/tmp/a", "", fileEventRemoved},
+		{"different proc path", "container1", "/tmp/a", "/bin/sh", fileEventModified},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := w.computeEventID(c.cid, c.path, c.event, c.procPath); got == id1 {
+				t.Errorf("expected a distinct event to produce a different ID than %q", id1)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/neuvector/neuvector/share"
+	"github.com/neuvector/neuvector/share/osutil"
 	"github.com/neuvector/neuvector/share/system"
 	"github.com/neuvector/neuvector/share/utils"
 )
@@ -32,6 +33,12 @@ type rootFd struct {
 	dirMonitorMap map[string]uint64    // mask:
 	rules         map[string]utils.Set // allowed processes
 
+	// dryRun makes calculateResponse always let a would-be-denied access
+	// through -- reporting it via ProcInfo.DryRun instead of returning deny to
+	// the kernel -- for validating an Enforce profile, see
+	// FsmonConfig.DryRunEnforce.
+	dryRun bool
+
 	// references
 	paths map[string]*IFile
 	dirs  map[string]*IFile
@@ -39,16 +46,17 @@ type rootFd struct {
 
 type FaNotify struct {
 	fNotify
-	bEnabled   bool
-	configPerm bool
-	agentPid   int
-	ourRootPid int
-	fa         *NotifyFD
-	roots      map[int]*rootFd
-	mntRoots   map[uint64]*rootFd
-	pidLookup  PidLookupCallback
-	sys        *system.SystemTools
-	endChan    chan bool
+	bEnabled        bool
+	configPerm      bool
+	agentPid        int
+	ourRootPid      int
+	fa              *NotifyFD
+	roots           map[int]*rootFd
+	mntRoots        map[uint64]*rootFd
+	pidLookup       PidLookupCallback
+	sys             *system.SystemTools
+	endChan         chan bool
+	decisionTimeout time.Duration
 }
 
 const faInitFlags = FAN_CLOEXEC | FAN_CLASS_CONTENT | FAN_UNLIMITED_MARKS
@@ -100,7 +108,7 @@ func (fn *FaNotify) checkConfigPerm() bool {
 }
 
 // set file monitor policy mode. but not update the watches. the upper layer need to re-add the watches.
-func (fn *FaNotify) SetMode(rootPid int, access, perm, capBlock, bNeuvectorSvc bool) {
+func (fn *FaNotify) SetMode(rootPid int, access, perm, capBlock, bNeuvectorSvc, dryRun bool) {
 	if !fn.bEnabled {
 		return
 	}
@@ -126,18 +134,34 @@ func (fn *FaNotify) SetMode(rootPid int, access, perm, capBlock, bNeuvectorSvc b
 		permControl:   perm,
 		capBlock:      capBlock,
 		bNeuVectorSvc: bNeuvectorSvc,
+		dryRun:        dryRun,
 	}
 
 	fn.roots[rootPid] = r
 	fn.mntRoots[fn.sys.GetMntNamespaceId(rootPid)] = r
 }
 
+// SetDecisionTimeout bounds how long a permission event's block-access
+// decision (lookupContainer plus lookupRule in calculateResponse) may run
+// before handleEvents answers the kernel with an allow default instead of
+// leaving the calling process hung. d <= 0 disables the deadline, running
+// calculateResponse synchronously as before.
+func (fn *FaNotify) SetDecisionTimeout(d time.Duration) {
+	if !fn.bEnabled {
+		return
+	}
+
+	fn.mux.Lock()
+	defer fn.mux.Unlock()
+	fn.decisionTimeout = d
+}
+
 func (fn *FaNotify) GetWatches() []*share.CLUSFileMonitorFile {
 	// not support
 	return make([]*share.CLUSFileMonitorFile, 0)
 }
 
-//////
+// ////
 func (fn *FaNotify) RemoveMonitorFile(path string) {
 	log.WithFields(log.Fields{"path": path}).Debug("FMON:")
 	fn.mux.Lock()
@@ -180,8 +204,9 @@ func (fn *FaNotify) RemoveMonitorFile(path string) {
 	}
 }
 
-//////
-//  note: ibm cloud does not support the FAN_MARK_FLUSH flag
+// ////
+//
+//	note: ibm cloud does not support the FAN_MARK_FLUSH flag
 func (fn *FaNotify) removeMarks(r *rootFd) {
 	// guarded by its calling function
 	// log.WithFields(log.Fields{"rootPid": rootPid}).Debug("FMON: cleanup")
@@ -220,7 +245,7 @@ func (fn *FaNotify) ContainerCleanup(rootPid int) {
 	}
 }
 
-/////
+// ///
 func (fn *FaNotify) monitorExit() {
 	if fn.fa != nil {
 		fn.fa.Close()
@@ -231,7 +256,7 @@ func (fn *FaNotify) monitorExit() {
 	}
 }
 
-/////
+// ///
 func (fn *FaNotify) Close() {
 	log.Debug("FMON: ")
 	if !fn.bEnabled {
@@ -256,6 +281,60 @@ func (fn *FaNotify) Close() {
 	}()
 }
 
+// ProtectFile escalates containerRelPath -- already individually watched via
+// AddMonitorFile, not merely covered as a child of a watched directory -- to
+// deny/permission mode, without rebuilding the rest of the group's profile.
+// It extends the enclosing directory's fanotify mark with the same
+// permission bits addFile would have set had protect been true from the
+// start, and flips the file's own IFile.protect flag so
+// calculateResponse/LookupPath/GetWatchFileList immediately treat it as
+// protected. Returns an error if rootPid isn't tracked or containerRelPath
+// isn't currently watched.
+func (fn *FaNotify) ProtectFile(rootPid int, containerRelPath string) error {
+	if !fn.bEnabled {
+		return fmt.Errorf("FMON: fanotify not enabled")
+	}
+
+	fn.mux.Lock()
+	defer fn.mux.Unlock()
+
+	r, ok := fn.roots[rootPid]
+	if !ok {
+		return fmt.Errorf("FMON: container not found, rootPid=%d", rootPid)
+	}
+
+	file, ok := r.paths[containerRelPath]
+	if !ok {
+		return fmt.Errorf("FMON: %s is not currently watched, rootPid=%d", containerRelPath, rootPid)
+	}
+	if file.protect {
+		return nil
+	}
+
+	var permBits uint64
+	if r.permControl {
+		if fn.configPerm {
+			permBits = FAN_OPEN_PERM
+		} else {
+			permBits = FAN_OPEN
+		}
+	} else {
+		permBits = FAN_OPEN
+	}
+
+	file.protect = true
+	file.mask |= permBits
+
+	dir := path.Dir(containerRelPath)
+	r.dirMonitorMap[dir] |= permBits
+
+	ppath := fmt.Sprintf(procRootMountPoint, r.pid)
+	if err := fn.fa.Mark(faMarkAddFlags, permBits, unix.AT_FDCWD, ppath+dir); err != nil {
+		return fmt.Errorf("FMON: failed to escalate fanotify mark for %s: %w", containerRelPath, err)
+	}
+	return nil
+}
+
 func (fn *FaNotify) GetWatchFileList(rootPid int) []*share.CLUSFileMonitorFile {
 	log.WithFields(log.Fields{"rootPid": rootPid}).Debug("FMON:")
 	watches := make([]*share.CLUSFileMonitorFile, 0)
@@ -289,6 +368,106 @@ func (fn *FaNotify) GetWatchFileList(rootPid int) []*share.CLUSFileMonitorFile {
 	return watches
 }
 
+// BaselineEntry is one watched path's recorded state, used by
+// FileWatch.ExportBaseline for offline diagnostics.
+type BaselineEntry struct {
+	Path    string
+	IsDir   bool
+	Mask    uint64
+	Protect bool
+	// Hash is the hex-encoded content hash recorded at walk/resync time, see
+	// osutil.FileInfoExt.Hash. Empty for a directory or a path never hashed.
+	Hash string
+}
+
+// DumpBaseline returns rootPid's current fanotify watch list -- paths,
+// directories and their recorded hashes -- for FileWatch.ExportBaseline.
+func (fn *FaNotify) DumpBaseline(rootPid int) []BaselineEntry {
+	fn.mux.Lock()
+	defer fn.mux.Unlock()
+
+	entries := make([]BaselineEntry, 0)
+	r, ok := fn.roots[rootPid]
+	if !ok {
+		return entries
+	}
+	hashOf := func(fl *IFile) string {
+		fi, _ := fl.params.(*osutil.FileInfoExt)
+		if fi == nil || osutil.HashZero(fi.Hash) {
+			return ""
+		}
+		return fmt.Sprintf("%x", fi.Hash)
+	}
+	for path, fl := range r.paths {
+		entries = append(entries, BaselineEntry{Path: path, Mask: fl.mask, Protect: fl.protect, Hash: hashOf(fl)})
+	}
+	for path, dir := range r.dirs {
+		entries = append(entries, BaselineEntry{Path: path, IsDir: true, Mask: dir.mask, Protect: dir.protect, Hash: hashOf(dir)})
+	}
+	return entries
+}
+
+// FilterCoverage maps each configured filter's index key (see filterIndexKey)
+// to the concrete paths currently watched because of it, so callers can prove
+// that a given filter -- e.g. the one covering /etc/shadow -- is genuinely
+// backed by an active mark rather than silently dropped.
+func (fn *FaNotify) FilterCoverage(rootPid int) map[string][]string {
+	coverage := make(map[string][]string)
+	fn.mux.Lock()
+	defer fn.mux.Unlock()
+	r, ok := fn.roots[rootPid]
+	if !ok {
+		return coverage
+	}
+	addCoverage := func(path string, fl *IFile) {
+		if fl.filter == nil {
+			return
+		}
+		key := fl.filter.path
+		coverage[key] = append(coverage[key], path)
+	}
+	for path, fl := range r.paths {
+		addCoverage(path, fl)
+	}
+	for path, dir := range r.dirs {
+		addCoverage(path, dir)
+		for f := range dir.files {
+			addCoverage(filepath.Join(path, f), dir)
+		}
+	}
+	return coverage
+}
+
+// LookupPath reports fanotify's current watch state for containerRelPath
+// under rootPid, without modifying anything: found is false unless
+// containerRelPath, or the directory covering it, is presently watched. info
+// is the FileInfoExt recorded for the path at the last walk or resync, or
+// nil if none was kept (e.g. a file merged into its parent dir's Children).
+func (fn *FaNotify) LookupPath(rootPid int, containerRelPath string) (info *osutil.FileInfoExt, isDir, protect, found bool) {
+	fn.mux.Lock()
+	defer fn.mux.Unlock()
+
+	r, ok := fn.roots[rootPid]
+	if !ok {
+		return nil, false, false, false
+	}
+	if fl, ok := r.paths[containerRelPath]; ok {
+		fi, _ := fl.params.(*osutil.FileInfoExt)
+		return fi, false, fl.protect, true
+	}
+	if dir, ok := r.dirs[containerRelPath]; ok {
+		fi, _ := dir.params.(*osutil.FileInfoExt)
+		return fi, true, dir.protect, true
+	}
+	if dir, ok := r.dirs[path.Dir(containerRelPath)]; ok {
+		if fp, ok := dir.files[path.Base(containerRelPath)]; ok {
+			fi, _ := fp.(*osutil.FileInfoExt)
+			return fi, false, dir.protect, true
+		}
+	}
+	return nil, false, false, false
+}
+
 // use the path prefix for container index
 func ParseMonitorPath(path string) (int, string, error) {
 	if a := strings.Index(path, "/root/"); a > 0 {
@@ -301,7 +480,7 @@ func ParseMonitorPath(path string) (int, string, error) {
 	return 0, "", fmt.Errorf("Invalid path")
 }
 
-/////
+// ///
 func (fn *FaNotify) addDirPath(r *rootFd, path string, bDir bool, mask uint64) {
 	// append monitor directory
 	dir := path
@@ -325,7 +504,7 @@ func (fn *FaNotify) addDirPath(r *rootFd, path string, bDir bool, mask uint64) {
 	return
 }
 
-////
+// //
 func (fn *FaNotify) AddMonitorFile(path string, filter interface{}, protect, userAdded bool, cb NotifyCallback, params interface{}) bool {
 	if !fn.bEnabled {
 		return false
@@ -333,7 +512,7 @@ func (fn *FaNotify) AddMonitorFile(path string, filter interface{}, protect, use
 	return fn.addFile(path, filter, protect, false, userAdded, nil, cb, params)
 }
 
-/////
+// ///
 func (fn *FaNotify) AddMonitorDirFile(path string, filter interface{}, protect, userAdded bool, files map[string]interface{}, cb NotifyCallback, params interface{}) bool {
 	if !fn.bEnabled {
 		return false
@@ -341,7 +520,7 @@ func (fn *FaNotify) AddMonitorDirFile(path string, filter interface{}, protect,
 	return fn.addFile(path, filter, protect, true, userAdded, files, cb, params)
 }
 
-//// TODO
+// // TODO
 func (fn *FaNotify) AddMonitorFileOnTheFly(path string, filter interface{}, protect, userAdded bool, cb NotifyCallback, params interface{}) bool {
 	if !fn.bEnabled {
 		return false
@@ -353,7 +532,7 @@ func (fn *FaNotify) AddMonitorFileOnTheFly(path string, filter interface{}, prot
 	return false
 }
 
-////
+// //
 func (fn *FaNotify) addSingleFile(r *rootFd, path string, mask uint64) bool {
 	if !fn.bEnabled {
 		return false
@@ -368,7 +547,7 @@ func (fn *FaNotify) addSingleFile(r *rootFd, path string, mask uint64) bool {
 	return true
 }
 
-////
+// //
 func (fn *FaNotify) addHostNetworkFilesCopiedFiles(r *rootFd) {
 	// only for /etc/ now: hosts, hostname, and resolv.conf
 	files := []string{"/etc/hosts", "/etc/hostname", "/etc/resolv.conf"}
@@ -384,7 +563,7 @@ func (fn *FaNotify) addHostNetworkFilesCopiedFiles(r *rootFd) {
 	}
 }
 
-/////
+// ///
 func (fn *FaNotify) StartMonitor(rootPid int) bool {
 	if !fn.bEnabled {
 		return false
@@ -414,7 +593,7 @@ func (fn *FaNotify) StartMonitor(rootPid int) bool {
 		if err := fn.fa.Mark(faMarkAddFlags, mask, unix.AT_FDCWD, path); err != nil {
 			log.WithFields(log.Fields{"path": path, "error": err}).Debug("FMON:")
 		} else {
-			mLog.WithFields(log.Fields{"path": path, "mask": fmt.Sprintf("0x%08x", mask)}).Debug("FMON:")
+			faLog.WithFields(log.Fields{"path": path, "mask": fmt.Sprintf("0x%08x", mask)}).Debug("FMON:")
 		}
 	}
 
@@ -423,7 +602,7 @@ func (fn *FaNotify) StartMonitor(rootPid int) bool {
 	return ok
 }
 
-//////
+// ////
 func (fn *FaNotify) addFile(path string, filter interface{}, protect, isDir, userAdded bool, files map[string]interface{}, cb NotifyCallback, params interface{}) bool {
 	if !fn.bEnabled {
 		return false
@@ -511,7 +690,7 @@ func (fn *FaNotify) addFile(path string, filter interface{}, protect, isDir, use
 	return true
 }
 
-/////
+// ///
 func (fn *FaNotify) MonitorFileEvents() {
 	waitCnt := 0
 	pfd := make([]unix.PollFd, 1)
@@ -548,7 +727,7 @@ func (fn *FaNotify) MonitorFileEvents() {
 	log.Info("FMON: exit")
 }
 
-//////
+// ////
 func (fn *FaNotify) handleEvents() error {
 	if events, err := fn.fa.GetEvents(); err == nil {
 		for _, ev := range events {
@@ -558,33 +737,95 @@ func (fn *FaNotify) handleEvents() error {
 			fmask := uint64(ev.Mask)
 			perm := (fmask & (FAN_OPEN_PERM | FAN_ACCESS_PERM)) > 0
 
+			if perm && fn.decisionTimeout > 0 {
+				fn.handlePermEventWithTimeout(ev, pid, fd, fmask)
+				continue
+			}
+
 			resp, mask, ifile, pInfo := fn.calculateResponse(pid, fd, fmask, perm)
 			if perm {
 				fn.fa.Response(ev, resp)
 			}
 			ev.File.Close()
 
-			if ifile == nil {
-				continue // nothing to justify
-			}
+			fn.reportEvent(ifile, mask, fmask, resp, pInfo)
+		}
+	}
+	return nil
+}
 
-			change := (fmask & FAN_CLOSE_WRITE) > 0
-			// log.WithFields(log.Fields{"ifile": ifile, "pInfo": pInfo, "Resp": resp, "Change": change, "Perm": perm}).Debug("FMON:")
+// handlePermEventWithTimeout answers a permission event (ev, already known to
+// need a response) the same way the synchronous path in handleEvents does,
+// except the decision runs in a goroutine raced against fn.decisionTimeout:
+// if calculateResponse doesn't finish first, the kernel is answered with an
+// allow default right away instead of leaving the calling process hung, and
+// a fileEventDenyTimeout report is emitted once the delayed decision
+// eventually completes, rather than the report calculateResponse's own
+// result would have produced.
+//
+// ev.File is closed exactly once, by whichever of the two paths -- this
+// timed wait, or the background goroutine that outlives it -- actually
+// observes calculateResponse's return; done's buffer of 1 guarantees exactly
+// one of them receives it.
+//
+// The request this implements also called for coordinating the timeout with
+// "the fanotify response loop invoked through SendNVProcessAlert", but no
+// such function exists anywhere in this codebase, so there's nothing to wire
+// that coordination into.
+func (fn *FaNotify) handlePermEventWithTimeout(ev *EventMetadata, pid, fd int, fmask uint64) {
+	type decision struct {
+		resp  bool
+		mask  uint32
+		ifile *IFile
+		pInfo *ProcInfo
+	}
 
-			var bReporting bool
-			if ifile.learnt { // discover mode
-				bReporting = ifile.userAdd // learn app for customer-added entry
-			} else { // monitor or protect mode
-				allowRead := resp && !change
-				bReporting = (allowRead == false) // allowed app by block_access
-			}
+	done := make(chan decision, 1)
+	go func() {
+		resp, mask, ifile, pInfo := fn.calculateResponse(pid, fd, fmask, true)
+		done <- decision{resp, mask, ifile, pInfo}
+	}()
 
-			if bReporting || change { // report changed file
-				ifile.cb(ifile.path, mask, ifile.params, pInfo)
+	select {
+	case d := <-done:
+		fn.fa.Response(ev, d.resp)
+		ev.File.Close()
+		fn.reportEvent(d.ifile, d.mask, fmask, d.resp, d.pInfo)
+	case <-time.After(fn.decisionTimeout):
+		fn.fa.Response(ev, true)
+		log.WithFields(log.Fields{"pid": pid, "timeout": fn.decisionTimeout}).Warn("FMON: permission decision timed out, defaulted to allow")
+		go func() {
+			d := <-done
+			ev.File.Close()
+			if d.ifile != nil {
+				d.ifile.cb(d.ifile.path, fileEventDenyTimeout, d.ifile.params, d.pInfo)
 			}
-		}
+		}()
+	}
+}
+
+// reportEvent runs ifile's callback when calculateResponse's outcome
+// warrants a report, the logic handleEvents ran inline before
+// handlePermEventWithTimeout needed to share it with the timed-out path too.
+func (fn *FaNotify) reportEvent(ifile *IFile, mask uint32, fmask uint64, resp bool, pInfo *ProcInfo) {
+	if ifile == nil {
+		return // nothing to justify
+	}
+
+	change := (fmask & FAN_CLOSE_WRITE) > 0
+	// log.WithFields(log.Fields{"ifile": ifile, "pInfo": pInfo, "Resp": resp, "Change": change}).Debug("FMON:")
+
+	var bReporting bool
+	if ifile.learnt { // discover mode
+		bReporting = ifile.userAdd // learn app for customer-added entry
+	} else { // monitor or protect mode
+		allowRead := resp && !change
+		bReporting = (allowRead == false) // allowed app by block_access
+	}
+
+	if bReporting || change { // report changed file
+		ifile.cb(ifile.path, mask, ifile.params, pInfo)
 	}
-	return nil
 }
 
 func (fn *FaNotify) calculateResponse(pid, fd int, fmask uint64, perm bool) (bool, uint32, *IFile, *ProcInfo) {
@@ -673,7 +914,14 @@ func (fn *FaNotify) calculateResponse(pid, fd int, fmask uint64, perm bool) (boo
 
 	if perm && !resp {
 		pInfo.Deny = true
-		log.WithFields(log.Fields{"path": linkPath, "app": pInfo.Path}).Debug("FMON: denied")
+		pInfo.DenyDir = ifile.dir
+		if r.dryRun {
+			// Validating an Enforce profile: report the denial but let the
+			// access through instead of actually blocking it.
+			pInfo.DryRun = true
+			resp = true
+		}
+		log.WithFields(log.Fields{"path": linkPath, "app": pInfo.Path, "dir": ifile.dir, "dryRun": r.dryRun}).Debug("FMON: denied")
 	}
 	return resp, mask, ifile, pInfo
 }
@@ -807,7 +1055,7 @@ func (fn *FaNotify) UpdateAccessRule(rootPid int, conf *share.CLUSFileAccessRule
 	return nil
 }
 
-////////
+// //////
 func (fn *FaNotify) GetProbeData(m *FaMonProbeData) {
 	fn.mux.Lock()
 	defer fn.mux.Unlock()
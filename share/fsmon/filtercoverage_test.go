@@ -0,0 +1,60 @@
+package fsmon
+
+import "testing"
+
+// TestFilterCoverage covers FaNotify.FilterCoverage: it must map each
+// filter's index key to every concrete path -- watched files, watched
+// directories, and files discovered inside a watched directory -- currently
+// resolved through it.
+func TestFilterCoverage(t *testing.T) {
+	etcFilter := &filterRegex{path: "/etc/*"}
+	binFilter := &filterRegex{path: "/bin/*"}
+
+	root := &rootFd{
+		paths: map[string]*IFile{
+			"/etc/shadow": {path: "/etc/shadow", filter: etcFilter},
+		},
+		dirs: map[string]*IFile{
+			"/etc/cron.d": {
+				path:   "/etc/cron.d",
+				dir:    true,
+				filter: etcFilter,
+				files:  map[string]interface{}{"jobs": nil},
+			},
+			"/bin": {
+				path:   "/bin",
+				dir:    true,
+				filter: binFilter,
+				files:  map[string]interface{}{"sh": nil},
+			},
+		},
+	}
+
+	fn := &FaNotify{roots: map[int]*rootFd{1: root}}
+
+	coverage := fn.FilterCoverage(1)
+
+	etcPaths := toSet(coverage[etcFilter.path])
+	for _, want := range []string{"/etc/shadow", "/etc/cron.d", "/etc/cron.d/jobs"} {
+		if !etcPaths[want] {
+			t.Errorf("expected %q to be covered by filter %q, got %v", want, etcFilter.path, coverage[etcFilter.path])
+		}
+	}
+
+	binPaths := toSet(coverage[binFilter.path])
+	if !binPaths["/bin"] || !binPaths["/bin/sh"] {
+		t.Errorf("expected /bin and /bin/sh to be covered by filter %q, got %v", binFilter.path, coverage[binFilter.path])
+	}
+
+	if got := fn.FilterCoverage(999); len(got) != 0 {
+		t.Errorf("expected no coverage for an unknown rootPid, got %v", got)
+	}
+}
+
+func toSet(paths []string) map[string]bool {
+	s := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		s[p] = true
+	}
+	return s
+}
@@ -1,8 +1,8 @@
 package fsmon
 
 import (
-	"fmt"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -25,18 +25,19 @@ const (
 		syscall.IN_DELETE_SELF |
 		syscall.IN_MOVE |
 		syscall.IN_MOVE_SELF
-	imonitorDirMask = imonitorFileMask | syscall.IN_MOVED_TO | syscall.IN_CREATE
+	imonitorDirMask    = imonitorFileMask | syscall.IN_MOVED_TO | syscall.IN_CREATE
 	imonitorRemoveMask = syscall.IN_DELETE | syscall.IN_DELETE_SELF | syscall.IN_MOVE | syscall.IN_MOVE_SELF
 )
 
 type Inotify struct {
 	fNotify
-	bEnabled bool
-	fd       int
-	wds      map[int]*IFile
-	paths    map[string]*IFile
-	dirs     map[string]*IFile
+	bEnabled    bool
+	fd          int
+	wds         map[int]*IFile
+	paths       map[string]*IFile
+	dirs        map[string]*IFile
 	inotifyFile *os.File
+	overflowCb  func()
 }
 
 func NewInotify() (*Inotify, error) {
@@ -47,15 +48,24 @@ func NewInotify() (*Inotify, error) {
 		return nil, err
 	}
 	in := Inotify{
-		fd:    fd,
+		fd:          fd,
 		inotifyFile: os.NewFile(uintptr(fd), ""),
-		wds:   make(map[int]*IFile),
-		paths: make(map[string]*IFile),
-		dirs:  make(map[string]*IFile),
+		wds:         make(map[int]*IFile),
+		paths:       make(map[string]*IFile),
+		dirs:        make(map[string]*IFile),
 	}
 	return &in, nil
 }
 
+// SetOverflowCallback registers cb to be invoked, without holding n.mux,
+// whenever MonitorFileEvents observes IN_Q_OVERFLOW -- the kernel signaling
+// that its inotify event queue filled up and events were dropped. The
+// overflow event carries no watch descriptor, so cb can't be told which
+// path was affected.
+func (n *Inotify) SetOverflowCallback(cb func()) {
+	n.overflowCb = cb
+}
+
 func (n *Inotify) GetWatchCount() uint32 {
 	return uint32(len(n.wds))
 }
@@ -86,6 +96,26 @@ func (n *Inotify) CheckMonitorFileExist(path string) (interface{}, bool) {
 	}
 }
 
+// IsWatched reports whether fullPath -- the full /proc/<rootPid>/root/-
+// prefixed path fsmon uses internally -- is currently watched by inotify,
+// either directly or as an entry under a watched directory.
+func (n *Inotify) IsWatched(fullPath string) bool {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	if _, ok := n.paths[fullPath]; ok {
+		return true
+	}
+	if _, ok := n.dirs[fullPath]; ok {
+		return true
+	}
+	if dir, ok := n.dirs[filepath.Dir(fullPath)]; ok {
+		if _, ok := dir.files[filepath.Base(fullPath)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *Inotify) RemoveMonitorFile(path string) {
 	log.WithFields(log.Fields{"path": path}).Debug("")
 	n.mux.Lock()
@@ -103,7 +133,7 @@ func (n *Inotify) RemoveMonitorFile(path string) {
 	// the file might be a subdir in the watched dir
 	dirPath := filepath.Dir(path)
 	if ifd, exist := n.dirs[dirPath]; exist {
-		mLog.WithFields(log.Fields{"dir": filepath.Base(path)}).Debug("remove subdir from dir")
+		inLog.WithFields(log.Fields{"dir": filepath.Base(path)}).Debug("remove subdir from dir")
 		delete(ifd.files, filepath.Base(path))
 	}
 }
@@ -144,7 +174,7 @@ func (n *Inotify) ContainerCleanup(rootPid int) {
 			syscall.InotifyRmWatch(n.fd, uint32(ifl.wd))
 			delete(n.wds, ifl.wd)
 			delete(n.paths, path)
-			mLog.WithFields(log.Fields{"path": path}).Debug("Delete file path")
+			inLog.WithFields(log.Fields{"path": path}).Debug("Delete file path")
 		}
 	}
 	for path, ifl := range n.dirs {
@@ -152,7 +182,7 @@ func (n *Inotify) ContainerCleanup(rootPid int) {
 			syscall.InotifyRmWatch(n.fd, uint32(ifl.wd))
 			delete(n.wds, ifl.wd)
 			delete(n.dirs, path)
-			mLog.WithFields(log.Fields{"path": path}).Debug("Delete dir path")
+			inLog.WithFields(log.Fields{"path": path}).Debug("Delete dir path")
 		}
 	}
 }
@@ -176,14 +206,14 @@ func (n *Inotify) AddMonitorFile(path string, cb NotifyCallback, params interfac
 	}
 	n.wds[wd] = &file
 	n.paths[path] = &file
-	mLog.WithFields(log.Fields{"count": len(n.paths), "path": path}).Debug()
+	inLog.WithFields(log.Fields{"count": len(n.paths), "path": path}).Debug()
 	return true
 }
 
 // Without Lock
 func (n *Inotify) addMonitorDir(path string, files map[string]interface{}, cb NotifyCallback, params interface{}) bool {
 	if ifile, ok := n.dirs[path]; ok {
-		// mLog.WithFields(log.Fields{"path": path}).Debug()
+		// inLog.WithFields(log.Fields{"path": path}).Debug()
 		ifile.files = files
 	} else {
 		wd, err := syscall.InotifyAddWatch(n.fd, path, imonitorDirMask)
@@ -201,7 +231,7 @@ func (n *Inotify) addMonitorDir(path string, files map[string]interface{}, cb No
 		}
 		n.wds[wd] = ifile
 		n.dirs[path] = ifile
-		mLog.WithFields(log.Fields{"counts": len(n.dirs), "dir": path}).Debug()
+		inLog.WithFields(log.Fields{"counts": len(n.dirs), "dir": path}).Debug()
 	}
 	return true
 }
@@ -222,7 +252,7 @@ func (n *Inotify) MonitorFileEvents() {
 
 		bytesRead, err := n.inotifyFile.Read(buffer[:])
 		// bytesRead, err := syscall.Read(n.fd, buffer)
-		if err != nil  || bytesRead < syscall.SizeofInotifyEvent {
+		if err != nil || bytesRead < syscall.SizeofInotifyEvent {
 			if errors.Unwrap(err) == os.ErrClosed || strings.Contains(err.Error(), "bad file descriptor") {
 				log.WithFields(log.Fields{"err": err}).Error("Read Inotify")
 				break
@@ -234,6 +264,16 @@ func (n *Inotify) MonitorFileEvents() {
 		offset := 0
 		for offset <= bytesRead-syscall.SizeofInotifyEvent {
 			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+
+			if (event.Mask & syscall.IN_Q_OVERFLOW) > 0 {
+				log.Error("FMON: inotify event queue overflow, events lost")
+				if n.overflowCb != nil {
+					n.overflowCb()
+				}
+				offset += syscall.SizeofInotifyEvent + int(event.Len)
+				continue
+			}
+
 			var cbFile *IFile
 			n.mux.Lock()
 			if ifile, found := n.wds[int(event.Wd)]; found {
@@ -243,21 +283,21 @@ func (n *Inotify) MonitorFileEvents() {
 						nameLen := uint32(event.Len)
 						if nameLen > 0 {
 							bytes := (*[unix.PathMax]byte)(unsafe.Pointer(&buffer[offset+unix.SizeofInotifyEvent]))
-						    path = filepath.Join(ifile.path, strings.TrimRight(string(bytes[0:nameLen]), "\000"))
+							path = filepath.Join(ifile.path, strings.TrimRight(string(bytes[0:nameLen]), "\000"))
 						}
 
 						if (event.Mask & syscall.IN_ISDIR) > 0 {
-							mLog.WithFields(log.Fields{"dir": path, "mask": strconv.FormatUint(uint64(event.Mask), 16), "nameLen": nameLen}).Debug("dir: altered")
-							if (event.Mask & (syscall.IN_CREATE|syscall.IN_MOVED_TO)) > 0 {
-								cbFile = &IFile{ path: path, cb:ifile.cb, params: ifile.params}
+							inLog.WithFields(log.Fields{"dir": path, "mask": strconv.FormatUint(uint64(event.Mask), 16), "nameLen": nameLen}).Debug("dir: altered")
+							if (event.Mask & (syscall.IN_CREATE | syscall.IN_MOVED_TO)) > 0 {
+								cbFile = &IFile{path: path, cb: ifile.cb, params: ifile.params}
 
 								// new dir
 								if info, err := os.Stat(path); err == nil {
-									finfo := ifile.params.(*osutil.FileInfoExt)	// original FileInfoExt
+									finfo := ifile.params.(*osutil.FileInfoExt) // original FileInfoExt
 									flt := finfo.Filter.(*filterRegex)
 									if flt.recursive {
 										ff := make(map[string]interface{})
-										dirInfo := &osutil.FileInfoExt {
+										dirInfo := &osutil.FileInfoExt{
 											ContainerId: finfo.ContainerId,
 											FileMode:    info.Mode(),
 											Path:        path,
@@ -271,20 +311,20 @@ func (n *Inotify) MonitorFileEvents() {
 							} else if (event.Mask & syscall.IN_ATTRIB) > 0 {
 								if nameLen == 0 {
 									// skip directory meta changed
-									// mLog.WithFields(log.Fields{"dir": path}).Debug("dir: meta")
+									// inLog.WithFields(log.Fields{"dir": path}).Debug("dir: meta")
 									cbFile = &IFile{path: path, cb: ifile.cb, params: ifile.params}
 								}
-							} else if (event.Mask & (syscall.IN_DELETE|syscall.IN_MOVED_FROM)) > 0 {
-								// mLog.WithFields(log.Fields{"dir": path}).Debug("dir: deleted/moved")
-								cbFile = &IFile{path: path,	cb: ifile.cb, params: ifile.params}
+							} else if (event.Mask & (syscall.IN_DELETE | syscall.IN_MOVED_FROM)) > 0 {
+								// inLog.WithFields(log.Fields{"dir": path}).Debug("dir: deleted/moved")
+								cbFile = &IFile{path: path, cb: ifile.cb, params: ifile.params}
 							} else {
-								mLog.WithFields(log.Fields{"dir": path}).Debug("dir: not handled")
+								inLog.WithFields(log.Fields{"dir": path}).Debug("dir: not handled")
 							}
-						} else {  // a file under a watched directory
-							mLog.WithFields(log.Fields{"path": path, "mask": strconv.FormatUint(uint64(event.Mask), 16)}).Debug("dir: changed")
-							cbFile = &IFile{ path: path, cb: ifile.cb, params: ifile.params}
+						} else { // a file under a watched directory
+							inLog.WithFields(log.Fields{"path": path, "mask": strconv.FormatUint(uint64(event.Mask), 16)}).Debug("dir: changed")
+							cbFile = &IFile{path: path, cb: ifile.cb, params: ifile.params}
 						}
-					} else {  // a watched file
+					} else { // a watched file
 						if (event.Mask & imonitorRemoveMask) > 0 {
 							log.WithFields(log.Fields{"path": ifile.path}).Debug("file: remove")
 							syscall.InotifyRmWatch(n.fd, uint32(event.Wd))
@@ -324,7 +364,7 @@ func (n *Inotify) Close() {
 	n.bEnabled = false
 }
 
-////////
+// //////
 func (n *Inotify) GetProbeData(m *IMonProbeData) {
 	n.mux.Lock()
 	defer n.mux.Unlock()
@@ -1,18 +1,27 @@
 package fsmon
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/pmezard/go-difflib/difflib"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/semaphore"
 
 	"github.com/neuvector/neuvector/agent/workerlet"
 	"github.com/neuvector/neuvector/share"
@@ -21,9 +30,16 @@ import (
 	"github.com/neuvector/neuvector/share/utils"
 )
 
-////
+// //
 var mLog *log.Logger = log.New()
 
+// faLog and inLog are the fanotify and inotify sublogger equivalents of
+// mLog, so their trace levels can be toggled independently -- see
+// SetMonitorTrace -- e.g. verbose fanotify tracing without also drowning in
+// inotify debug lines.
+var faLog *log.Logger = log.New()
+var inLog *log.Logger = log.New()
+
 const inodeChangeMask = syscall.IN_CLOSE_WRITE |
 	syscall.IN_DELETE |
 	syscall.IN_DELETE_SELF |
@@ -33,6 +49,21 @@ const inodeChangeMask = syscall.IN_CLOSE_WRITE |
 
 const inodeMovedMask = syscall.IN_MOVE | syscall.IN_MOVE_SELF | syscall.IN_MOVED_TO
 
+// fileChurnCreateMask and fileChurnRemoveMask are the raw inotify bits
+// isFileChurn looks for together in the same aggregated fmod.mask to
+// recognize a path created (or moved in) and then removed within one
+// aggregation window, e.g. a build tool's temp files. See
+// FileMonitorConfig.DetectFileChurn.
+const fileChurnCreateMask = syscall.IN_CREATE | syscall.IN_MOVED_TO
+const fileChurnRemoveMask = syscall.IN_DELETE
+
+// isFileChurn reports whether mask carries both a create-type and a
+// delete-type bit, i.e. the net effect of the aggregation window is no
+// surviving file rather than an ordinary standalone deletion.
+func isFileChurn(mask uint32) bool {
+	return mask&fileChurnCreateMask > 0 && mask&fileChurnRemoveMask > 0
+}
+
 var packageFile utils.Set = utils.NewSet(
 	"/var/lib/dpkg/status",
 	"/var/lib/rpm/Packages",
@@ -41,6 +72,85 @@ var packageFile utils.Set = utils.NewSet(
 
 type SendAggregateReportCallback func(fsmsg *MonitorMessage) bool
 
+// SendBatchReportCallback delivers a batch of accumulated MonitorMessages at
+// once, see FileMonitorConfig.SendReportBatch.
+type SendBatchReportCallback func(fsmsgs []*MonitorMessage) bool
+
+// NetworkActivityHint reports the last time the container rooted at rootPid
+// had outbound network activity, see FileMonitorConfig.NetworkActivityHint.
+// A zero return value means no recent activity is known.
+type NetworkActivityHint func(rootPid int) (lastConn time.Time)
+
+// RawEventCallback receives every raw file event as it arrives from
+// fanotify/inotify, before it's coalesced into an aggregated report. See
+// FileMonitorConfig.RawEventCallback.
+type RawEventCallback func(cid, path string, mask uint32, pInfo *ProcInfo)
+
+// PathSeverityCallback computes MonitorMessage.Severity for an event on
+// path. critical is the value already computed by pathCritical. See
+// FileMonitorConfig.PathSeverity and defaultPathSeverity.
+type PathSeverityCallback func(path string, event uint32, critical bool) string
+
+// BaselineReadyCallback is invoked once a container's initial baseline walk
+// and watch setup completes, see FileMonitorConfig.BaselineReadyCallback.
+type BaselineReadyCallback func(cid string, rootPid int, fileCount int)
+
+// ImageLookupCallback resolves a container ID to its image reference and
+// container name, e.g. from the agent's container inventory, for
+// MonitorMessage.Image/ContainerName. See FileMonitorConfig.ImageLookup.
+type ImageLookupCallback func(cid string) (image, name string)
+
+// AggregationMode controls how cbNotify accumulates raw events for the same
+// path into fileEvents, and how sendMsg reports the result, see
+// FileMonitorConfig.Aggregation.
+type AggregationMode int
+
+const (
+	// AggregationDefault merges every raw event seen for a path within a
+	// drain interval into one fileMod -- their masks are OR'd together --
+	// and sendMsg emits one message per distinct process that touched the
+	// path. This is the behavior fsmon has always had.
+	AggregationDefault AggregationMode = iota
+	// AggregationByEvent keeps raw events with different masks on the same
+	// path from merging: each distinct mask gets its own fileMod, so a path
+	// that saw two distinct raw event types in one drain interval produces
+	// two messages, even when the same process caused both.
+	AggregationByEvent
+	// AggregationByPath collapses every event on a path within a drain
+	// interval into a single message, regardless of how many distinct
+	// processes touched it.
+	AggregationByPath
+)
+
+// sensitiveCredentialPaths are always reported at share.VulnSeverityCritical
+// by defaultPathSeverity, regardless of event type.
+var sensitiveCredentialPaths = []*regexp.Regexp{
+	regexp.MustCompile(`^/etc/shadow$`),
+	regexp.MustCompile(`^/etc/passwd$`),
+	regexp.MustCompile(`^/etc/gshadow$`),
+	regexp.MustCompile(`(^|/)\.ssh/`),
+}
+
+// defaultPathSeverity is the default PathSeverityCallback, used when
+// FileMonitorConfig.PathSeverity is nil. It rates critical mounts and
+// credential files as share.VulnSeverityCritical, package database files
+// (osutil.IsPackageLib) as share.VulnSeverityHigh, and everything else as
+// share.VulnSeverityMedium.
+func defaultPathSeverity(path string, event uint32, critical bool) string {
+	if critical {
+		return share.VulnSeverityCritical
+	}
+	for _, regx := range sensitiveCredentialPaths {
+		if regx.MatchString(path) {
+			return share.VulnSeverityCritical
+		}
+	}
+	if osutil.IsPackageLib(path) {
+		return share.VulnSeverityHigh
+	}
+	return share.VulnSeverityMedium
+}
+
 var ImportantFiles []share.CLUSFileMonitorFilter = []share.CLUSFileMonitorFilter{
 	share.CLUSFileMonitorFilter{Behavior: share.FileAccessBehaviorMonitor, Path: "/var/lib/dpkg/status", Regex: ""},
 	share.CLUSFileMonitorFilter{Behavior: share.FileAccessBehaviorMonitor, Path: "/var/lib/rpm/Packages", Regex: ""},
@@ -88,68 +198,524 @@ const (
 	fileEventDirRemoved
 	fileEventAccessed
 	fileEventDenied
+	fileEventDirDenied
 	fileEventMovedFrom
 	fileEventMovedTo
 	fileEventDirMovedFrom
 	fileEventDirMovedTo
+	fileEventCapability
+	fileEventChmod           // permission bits changed, see handleFileEvents
+	fileEventChown           // owning uid or gid changed, see handleFileEvents
+	fileEventTypeChanged     // a watched regular file became a symlink or vice versa, see handleFileEvents
+	fileEventSuppressed      // synthetic: summarizes reports withheld by the per-container report cap
+	fileEventNewMountNS      // synthetic: a monitored process is running in a mount namespace we haven't seen before
+	fileEventPackageActivity // synthetic: summarizes a burst of package-manager-path events, see recordPackageActivity
+	fileEventTransient       // synthetic: a path was created and removed within the same aggregation window, see FileMonitorConfig.DetectFileChurn
+	fileEventDenyTimeout     // synthetic: a block-access decision didn't finish within FaNotify's decision deadline and defaulted to allow, see FaNotify.SetDecisionTimeout
 )
 
 var fileEventMsg = map[uint32]string{
-	fileEventAttr:           "File attribute is changed.",
-	fileEventDirAttr:        "Directory attribute is changed.",
-	fileEventModified:       "File was modified.",
-	fileEventReplaced:       "File was replaced.",
-	fileEventCreate:         "File created in watched directory.",
-	fileEventRemoved:        "File deleted from watched directory.",
-	fileEventSymCreate:      "File symlink was created.",
-	fileEventSymModified:    "File symlink was modified.",
-	fileEventDirSymCreate:   "Directory symlink was created.",
-	fileEventDirSymModified: "Directory symlink was modified.",
-	fileEventDirCreate:      "Directory was created.",
-	fileEventDirRemoved:     "Directory was deleted.",
-	fileEventAccessed:       "File was accessed.",
-	fileEventDenied:         "File access was denied.",
-	fileEventMovedFrom:      "File was moved from.",
-	fileEventMovedTo:        "File was moved to.",
-	fileEventDirMovedFrom:   "Directory was moved from.",
-	fileEventDirMovedTo:     "Directory was moved to.",
+	fileEventAttr:            "File attribute is changed.",
+	fileEventDirAttr:         "Directory attribute is changed.",
+	fileEventModified:        "File was modified.",
+	fileEventReplaced:        "File was replaced.",
+	fileEventCreate:          "File created in watched directory.",
+	fileEventRemoved:         "File deleted from watched directory.",
+	fileEventSymCreate:       "File symlink was created.",
+	fileEventSymModified:     "File symlink was modified.",
+	fileEventDirSymCreate:    "Directory symlink was created.",
+	fileEventDirSymModified:  "Directory symlink was modified.",
+	fileEventDirCreate:       "Directory was created.",
+	fileEventDirRemoved:      "Directory was deleted.",
+	fileEventAccessed:        "File was accessed.",
+	fileEventDenied:          "File access was denied.",
+	fileEventDirDenied:       "Directory listing was denied.",
+	fileEventMovedFrom:       "File was moved from.",
+	fileEventMovedTo:         "File was moved to.",
+	fileEventDirMovedFrom:    "Directory was moved from.",
+	fileEventDirMovedTo:      "Directory was moved to.",
+	fileEventCapability:      "File gained Linux capabilities.",
+	fileEventChmod:           "File permission bits were changed.",
+	fileEventChown:           "File ownership was changed.",
+	fileEventTypeChanged:     "File was swapped for a symlink (or a symlink was swapped for a file).",
+	fileEventPackageActivity: "Package database modified.",
+	fileEventTransient:       "File created and removed in watched directory (transient).",
+	fileEventDenyTimeout:     "Block-access decision timed out and defaulted to allow.",
+}
+
+// isDeniedEvent reports whether event is one of the enforce-mode denial
+// events, fileEventDenied or fileEventDirDenied.
+func isDeniedEvent(event uint32) bool {
+	return event == fileEventDenied || event == fileEventDirDenied
 }
 
 type SendFileAccessRuleCallback func(rules []*share.CLUSFileAccessRuleReq) error
 type EstimateRuleSrcCallback func(id, path string, bBlocked bool) string
 
 type fileMod struct {
-	mask  uint32
-	delay int
-	finfo *osutil.FileInfoExt
-	pInfo []*ProcInfo
+	mask      uint32
+	delay     int
+	finfo     *osutil.FileInfoExt
+	pInfo     []*ProcInfo
+	anomalous bool // observed rate exceeds the path's learned baseline
+
+	// extra holds additional event groups accumulated for the same path
+	// under AggregationByEvent: a raw mask that doesn't match this fileMod's
+	// own mask gets its own entry here instead of being OR'd in, so it's
+	// reported as a separate message. Always empty under any other
+	// AggregationMode. See cbNotify and HandleWatchedFiles.
+	extra []*fileMod
+}
+
+// pathRateStat tracks a path's observed event rate so adaptive throttling can
+// tell a normally-busy file from one that's suddenly spiking.
+type pathRateStat struct {
+	windowStart time.Time
+	count       int
+	baseline    float64 // EWMA of events per window
+}
+
+// containerReportBucket implements a simple token-bucket over a container's
+// reports within a rolling window, see FileMonitorConfig.MaxReportsPerContainer.
+type containerReportBucket struct {
+	windowStart time.Time
+	count       int
+	suppressed  int // reports withheld so far in the current window
+}
+
+// pkgActivityBucket accumulates package-manager-path events for one
+// container within packageCoalesceWindow, see recordPackageActivity.
+type pkgActivityBucket struct {
+	windowStart time.Time
+	count       int
+}
+
+// eventSampleBucket tracks FileMonitorConfig.EventSampleRate bookkeeping for
+// one event type within a rolling window, see allowSample.
+type eventSampleBucket struct {
+	windowStart time.Time
+	count       int
+	suppressed  int // occurrences sampled out so far in the current window
+}
+
+// hashCacheEntry caches osutil.GetFileHash's result for one path, see
+// FileMonitorConfig.HashCacheTTL and cachedFileHash. It's only reused while
+// size and modTime still match the file state that produced hash.
+type hashCacheEntry struct {
+	hash     [8]byte
+	size     int64
+	modTime  time.Time
+	cachedAt time.Time
 }
 
 type groupInfo struct {
-	bNeuvector bool
-	profile    *share.CLUSFileMonitorProfile
-	mode       string
-	applyRules map[string]utils.Set
-	learnRules map[string]utils.Set
-	startAt    time.Time
+	cid            string // container id, as passed to StartWatch
+	bNeuvector     bool
+	profile        *share.CLUSFileMonitorProfile
+	mode           string
+	applyRules     map[string]utils.Set
+	learnRules     map[string]utils.Set
+	startAt        time.Time
+	excludes       []*regexp.Regexp // compiled from FsmonConfig.ExcludePaths
+	criticalMounts []string         // from FsmonConfig.SensitiveMounts, see pathCritical
+	rootMntNs      uint64           // mount namespace of rootPid, see detectNewMountNamespaces
+	seenMntNs      map[uint64]bool  // mount namespaces already reported, see detectNewMountNamespaces
+	walkTimeout    time.Duration    // from FsmonConfig.WalkTimeout, reused by AddFilter
+	capBlock       bool             // from StartWatch, reused by OverrideMode
+	paused         bool             // see FileWatch.PauseContainer
+	dryRun         bool             // from FsmonConfig.DryRunEnforce, reused by OverrideMode
+}
+
+// compileExcludePaths compiles the configured exclusion patterns, logging and
+// skipping any pattern that fails to compile rather than failing StartWatch.
+func compileExcludePaths(patterns []string) []*regexp.Regexp {
+	if len(patterns) == 0 {
+		return nil
+	}
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		regx, err := regexp.Compile(p)
+		if err != nil {
+			log.WithFields(log.Fields{"pattern": p, "error": err}).Error("FMON: invalid exclude pattern")
+			continue
+		}
+		compiled = append(compiled, regx)
+	}
+	return compiled
+}
+
+// pathExcluded reports whether path matches any of the group's exclusion patterns.
+func pathExcluded(excludes []*regexp.Regexp, path string) bool {
+	for _, regx := range excludes {
+		if regx.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathCritical reports whether path is, or is under, one of the container's
+// sensitive mounts -- e.g. a bind-mounted container runtime socket, or /proc
+// or /sys -- as configured in FsmonConfig.SensitiveMounts. A write there is a
+// strong signal of a container breakout attempt, so callers flag matching
+// events with critical severity rather than the filter's normal behavior.
+func pathCritical(mounts []string, path string) bool {
+	for _, m := range mounts {
+		if m == "" {
+			continue
+		}
+		if path == m || strings.HasPrefix(path, strings.TrimSuffix(m, "/")+"/") {
+			return true
+		}
+	}
+	return false
 }
 
 type FileWatch struct {
-	mux        sync.Mutex
-	bEnable    bool // profile function is enabled, default: true
-	aufs       bool
-	fanotifier *FaNotify
-	inotifier  *Inotify
-	fileEvents map[string]*fileMod
-	groups     map[int]*groupInfo
-	sendrpt    SendAggregateReportCallback
-	sendRule   SendFileAccessRuleCallback
-	estRuleSrc EstimateRuleSrcCallback
-	walkerTask *workerlet.Tasker
+	mux            sync.Mutex
+	bEnable        bool // profile function is enabled, default: true
+	aufs           bool
+	fanotifier     *FaNotify
+	inotifier      *Inotify
+	fileEvents     map[string]*fileMod
+	groups         map[int]*groupInfo
+	sendrpt        SendAggregateReportCallback
+	sendRule       SendFileAccessRuleCallback
+	estRuleSrc     EstimateRuleSrcCallback
+	walkerTask     *workerlet.Tasker
+	reportInterval time.Duration
+	learnInterval  time.Duration
+	tickerReset    chan bool
+	done           chan struct{} // closed by Close to stop loop()
+	rateStats      map[string]*pathRateStat
+	throttleFactor float64
+	throttleWindow time.Duration
+	eventIDBucket  time.Duration
+	reportBuckets  map[string]*containerReportBucket
+	maxReports     int // FileMonitorConfig.MaxReportsPerContainer
+
+	// eventSampleRate and sampleBuckets implement the optional per-event-type
+	// sampling policy, see FileMonitorConfig.EventSampleRate and allowSample.
+	// sampleBuckets is guarded by mux, same as reportBuckets.
+	eventSampleRate map[uint32]int
+	sampleBuckets   map[uint32]*eventSampleBucket
+
+	// coalescePackages and pkgActivity implement FileMonitorConfig.
+	// CoalescePackageEvents, see recordPackageActivity.
+	coalescePackages bool
+	pkgActivity      map[string]*pkgActivityBucket
+
+	// sendBatch, batchMaxSize and batchFlushInterval implement the optional
+	// batched delivery path, see FileMonitorConfig.SendReportBatch. batchBuf
+	// and batchFirstAt track the currently-accumulating batch, guarded by
+	// batchMu rather than mux since they're flushed from the loop() ticker
+	// independently of the monitor's main lock.
+	sendBatch          SendBatchReportCallback
+	batchMaxSize       int
+	batchFlushInterval time.Duration
+	batchMu            sync.Mutex
+	batchBuf           []*MonitorMessage
+	batchFirstAt       time.Time
+
+	// watchBudget, budgetMu and watchCount implement the node-wide watch cap,
+	// see FileMonitorConfig.MaxWatches and admitWatch/releaseWatch. budgetMu
+	// is separate from mux since admission happens on the addFile/addDir
+	// hot path, independently of the group/event state mux guards.
+	watchBudget int
+	budgetMu    sync.Mutex
+	watchCount  int
+
+	// topPathMu and topPathCounts back TopPaths/ResetTopPaths, tallying how
+	// often each container-relative path is reported by sendMsg since the
+	// last reset.
+	topPathMu     sync.Mutex
+	topPathCounts map[string]int64
+
+	// maxWatchesPerContainer, containerWatchMu, containerWatchCount and
+	// containerTruncated implement the per-container watch cap, see
+	// FileMonitorConfig.MaxWatchesPerContainer and
+	// admitContainerWatch/releaseContainerWatch. containerWatchMu is
+	// separate from mux for the same reason budgetMu is.
+	maxWatchesPerContainer int
+	containerWatchMu       sync.Mutex
+	containerWatchCount    map[string]int
+	containerTruncated     map[string]bool
+
+	// walkerLimiter bounds how many rootfs walks may be in flight at once
+	// (see defaultWalkerConcurrency); walkAcquireTimeout bounds how long a
+	// walk waits for a slot before giving up, see
+	// FileMonitorConfig.WalkAcquireTimeout.
+	walkerLimiter      *semaphore.Weighted
+	walkAcquireTimeout time.Duration
+
+	// networkActivityHint is consulted by sendMsg to correlate file events
+	// with recent network activity, see FileMonitorConfig.NetworkActivityHint.
+	networkActivityHint NetworkActivityHint
+	// rawEventCallback is invoked by cbNotify for every raw event, see
+	// FileMonitorConfig.RawEventCallback.
+	rawEventCallback RawEventCallback
+
+	// maxPendingEvents and droppedEvents implement the optional pending-event
+	// cap, see FileMonitorConfig.MaxPendingEvents. droppedEvents is guarded by
+	// mux, same as fileEvents.
+	maxPendingEvents int
+	droppedEvents    int64
+
+	// pathSeverity computes MonitorMessage.Severity in sendMsg, see
+	// FileMonitorConfig.PathSeverity.
+	pathSeverity PathSeverityCallback
+
+	// aggregationMode controls how cbNotify accumulates fileEvents and how
+	// sendMsg reports the result, see FileMonitorConfig.Aggregation.
+	aggregationMode AggregationMode
+
+	// captureDiff, diffMaxSize, diffCache and diffMu implement the optional
+	// diff-snippet capture, see FileMonitorConfig.CaptureDiff. diffCache holds
+	// each diffed path's last-seen content, keyed by fullPath, so the next
+	// modification has something to diff against; diffMu guards it
+	// independently of mux since it's only touched from handleFileEvents.
+	captureDiff bool
+	diffMaxSize int64
+	diffCache   map[string][]byte
+	diffMu      sync.Mutex
+
+	// hashCacheTTL, hashCacheSize, hashCache and hashCacheMu implement the
+	// optional per-path hash cache consulted by cachedFileHash before
+	// re-reading a file to hash it, see FileMonitorConfig.HashCacheTTL.
+	// hashCacheMu guards hashCache independently of mux since it's only
+	// touched from handleFileEvents.
+	hashCacheTTL  time.Duration
+	hashCacheSize int
+	hashCache     map[string]*hashCacheEntry
+	hashCacheMu   sync.Mutex
+
+	// totalEventsProcessed and totalReportsSent are cumulative counters since
+	// startup, exported via ExportMetrics. Updated with the atomic package so
+	// they're safe to read concurrently with the watcher loop.
+	totalEventsProcessed int64
+	totalReportsSent     int64
+
+	// lastResync tracks, per rootPid, the last time handleQueueOverflow
+	// re-baselined that container in response to an inotify queue overflow,
+	// guarded by mux same as groups. See resyncOverflowInterval.
+	lastResync map[int]time.Time
+
+	// pidLookup is used by sendMsg to attribute an inotify event -- which
+	// carries no process info -- to the container's main process on a
+	// best-effort basis, see attributeByPidLookup.
+	pidLookup PidLookupCallback
+
+	// upperDirs maps a container's aufs/overlay upperdir (the host path
+	// events under the container's writable layer actually resolve to) back
+	// to its rootPid, guarded by mux same as groups. Populated best-effort by
+	// StartWatch when w.aufs is set; see resolveByUpperDir.
+	upperDirs map[string]int
+
+	// filterEvents counts reported events per rootPid per matched filter
+	// (keyed by filterIndexKey), guarded by mux same as groups. See
+	// GetFilterStats.
+	filterEvents map[int]map[string]int64
+
+	// runtimeManagedPaths lists container-relative paths a container runtime
+	// injects/rewrites at start (and often again on IP changes, etc) rather
+	// than the image or the workload writing them, so they shouldn't be
+	// learned or reported as file events. From FileMonitorConfig.
+	// RuntimeManagedPaths, see isRunTimeAddedFile.
+	runtimeManagedPaths []string
+
+	// detectFileChurn and suppressFileChurn are from FileMonitorConfig's
+	// fields of the same name, consulted by handleDirEvents.
+	detectFileChurn   bool
+	suppressFileChurn bool
+
+	// imageLookup is from FileMonitorConfig.ImageLookup, consulted by
+	// sendMsg's resolveImage.
+	imageLookup ImageLookupCallback
+
+	// baselineReady is from FileMonitorConfig.BaselineReadyCallback,
+	// invoked by StartWatch once a container's baseline is established.
+	baselineReady BaselineReadyCallback
+
+	// createDebounceTicks is FileMonitorConfig.CreateDebounce rounded up to
+	// the nearest reportInterval tick, consulted by HandleWatchedFiles.
+	// Zero disables debouncing.
+	createDebounceTicks int
+}
+
+// resyncOverflowInterval rate-limits handleQueueOverflow's re-baseline walk
+// to at most once per container per minute, so a sustained overflow doesn't
+// trigger a resync storm.
+const resyncOverflowInterval = time.Minute
+
+const (
+	defaultThrottleFactor = 5.0
+	defaultThrottleWindow = time.Minute
+)
+
+// observePathRate records an event for path within the current rate window and
+// reports whether the window's event count already exceeds the path's learned
+// baseline by throttleFactor. Must be called with w.mux held.
+func (w *FileWatch) observePathRate(path string) bool {
+	if w.throttleFactor <= 0 {
+		return false
+	}
+	stat, ok := w.rateStats[path]
+	now := time.Now()
+	if !ok {
+		w.rateStats[path] = &pathRateStat{windowStart: now, count: 1}
+		return false
+	}
+
+	if now.Sub(stat.windowStart) >= w.throttleWindow {
+		// roll the window: fold the completed count into the EWMA baseline
+		if stat.baseline == 0 {
+			stat.baseline = float64(stat.count)
+		} else {
+			stat.baseline = stat.baseline*0.7 + float64(stat.count)*0.3
+		}
+		stat.windowStart = now
+		stat.count = 0
+	}
+	stat.count++
+
+	return stat.baseline > 0 && float64(stat.count) > stat.baseline*w.throttleFactor
+}
+
+// allowReport applies the per-container report cap configured via
+// FileMonitorConfig.MaxReportsPerContainer. It reports whether the caller may
+// send its message as-is, and returns a non-nil summary message when a
+// just-completed window withheld one or more reports, which the caller
+// should send instead (or in addition, if allow is also true).
+func (w *FileWatch) allowReport(cid string) (allow bool, summary *MonitorMessage) {
+	if w.maxReports <= 0 {
+		return true, nil
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	now := time.Now()
+	bucket, ok := w.reportBuckets[cid]
+	if !ok {
+		bucket = &containerReportBucket{windowStart: now}
+		w.reportBuckets[cid] = bucket
+	}
+
+	if now.Sub(bucket.windowStart) >= w.throttleWindow {
+		if bucket.suppressed > 0 {
+			summary = &MonitorMessage{
+				ID:      cid,
+				EventID: w.computeEventID(cid, "", fileEventSuppressed, ""),
+				Msg:     fmt.Sprintf("Suppressed %d file events (report rate limit)", bucket.suppressed),
+				Count:   bucket.suppressed,
+				Action:  share.PolicyActionViolate,
+				StartAt: bucket.windowStart,
+			}
+		}
+		bucket.windowStart = now
+		bucket.count = 0
+		bucket.suppressed = 0
+	}
+
+	bucket.count++
+	if bucket.count > w.maxReports {
+		bucket.suppressed++
+		return false, summary
+	}
+	return true, summary
+}
+
+// allowSample applies FileMonitorConfig.EventSampleRate, sampling event's
+// occurrences down to roughly 1-in-N when a rate is configured for it.
+// Critical events and denials are never sampled out. It reports whether the
+// caller may send its message as-is, and returns a non-nil summary message
+// when a just-completed window sampled out one or more occurrences, which
+// the caller should send instead (or in addition, if allow is also true).
+func (w *FileWatch) allowSample(cid string, event uint32, critical bool, action string) (allow bool, summary *MonitorMessage) {
+	if critical || action == share.PolicyActionDeny || action == policyActionWouldDeny {
+		return true, nil
+	}
+
+	rate := w.eventSampleRate[event]
+	if rate <= 1 {
+		return true, nil
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	now := time.Now()
+	bucket, ok := w.sampleBuckets[event]
+	if !ok {
+		bucket = &eventSampleBucket{windowStart: now}
+		w.sampleBuckets[event] = bucket
+	}
+
+	if now.Sub(bucket.windowStart) >= w.throttleWindow {
+		if bucket.suppressed > 0 {
+			summary = &MonitorMessage{
+				ID:      cid,
+				EventID: w.computeEventID(cid, "", fileEventSuppressed, ""),
+				Msg:     fmt.Sprintf("Sampled out %d %q events (1-in-%d)", bucket.suppressed, fileEventMsg[event], rate),
+				Count:   bucket.suppressed,
+				Action:  share.PolicyActionViolate,
+				StartAt: bucket.windowStart,
+			}
+		}
+		bucket.windowStart = now
+		bucket.count = 0
+		bucket.suppressed = 0
+	}
+
+	bucket.count++
+	if bucket.count%rate != 0 {
+		bucket.suppressed++
+		return false, summary
+	}
+	return true, summary
+}
+
+// recordPackageActivity tallies a package-manager-path event for cid and,
+// once packageCoalesceWindow has elapsed since the first event of the
+// current window, reports a single summarized MonitorMessage for the
+// just-completed window instead of one per file -- a package install or
+// upgrade otherwise floods /var/lib/dpkg, /var/lib/rpm or /lib/apk with
+// dozens of individual reports. Mirrors allowReport's lazy-flush-on-next-
+// event bucket pattern.
+func (w *FileWatch) recordPackageActivity(cid string) {
+	w.mux.Lock()
+	now := time.Now()
+	bucket, ok := w.pkgActivity[cid]
+	if !ok {
+		bucket = &pkgActivityBucket{windowStart: now}
+		w.pkgActivity[cid] = bucket
+	}
+
+	var summary *MonitorMessage
+	if bucket.count > 0 && now.Sub(bucket.windowStart) >= packageCoalesceWindow {
+		summary = &MonitorMessage{
+			ID:      cid,
+			EventID: w.computeEventID(cid, "", fileEventPackageActivity, ""),
+			Package: true,
+			Msg:     fileEventMsg[fileEventPackageActivity],
+			Count:   bucket.count,
+			Action:  share.PolicyActionViolate,
+			StartAt: bucket.windowStart,
+		}
+		bucket.windowStart = now
+		bucket.count = 0
+	}
+	bucket.count++
+	w.mux.Unlock()
+
+	if summary != nil {
+		w.report(summary)
+	}
 }
 
 type MonitorMessage struct {
 	ID        string
+	EventID   string // stable content-addressed ID, see FileWatch.computeEventID
 	Path      string
 	Package   bool
 	ProcName  string
@@ -161,11 +727,41 @@ type MonitorMessage struct {
 	ProcPPid  int
 	ProcPName string
 	ProcPPath string
-	Group     string
-	Msg       string
-	Count     int
-	StartAt   time.Time
-	Action    string
+	// AttributionConfidence is "" when ProcName/ProcPath came from fanotify's
+	// exact permission-event process info, or "low" when they were filled in
+	// by attributeByPidLookup's best-effort fallback for an inotify event
+	// that carried no process info at all.
+	AttributionConfidence string
+	Group                 string
+	Msg                   string
+	Count                 int
+	StartAt               time.Time
+	Action                string
+	Anomalous             bool     // observed rate exceeds the path's learned baseline
+	Critical              bool     // path is a sensitive mount, see FsmonConfig.SensitiveMounts
+	OpenFDs               []string // modifying process's open files, see CLUSFileMonitorFilter.CaptureOpenFDs
+	ModeChanged           bool     // permission bits changed, see fileEventChmod
+	OwnerChanged          bool     // owning uid or gid changed, see fileEventChown
+	// NetworkCorrelated marks that the container had outbound network
+	// activity within networkCorrelationWindow before this event, per
+	// FileMonitorConfig.NetworkActivityHint -- a possible download-and-execute
+	// pattern.
+	NetworkCorrelated bool
+	// Severity classifies this event by how sensitive Path is, e.g. so
+	// downstream consumers can route a denial of /etc/shadow differently from
+	// an access of a random /usr/bin file. See defaultPathSeverity and
+	// FileMonitorConfig.PathSeverity.
+	Severity string
+	// Diff is a unified diff between the previous and new content of a
+	// modified text file, set only when FileMonitorConfig.CaptureDiff is
+	// enabled, the file is at or under DiffMaxSize, and a prior baseline was
+	// already cached to diff against. See handleFileEvents.
+	Diff string
+	// Image and ContainerName are the container's image reference and name,
+	// from FileMonitorConfig.ImageLookup. Both are empty if ImageLookup is
+	// nil or doesn't recognize ID.
+	Image         string
+	ContainerName string
 }
 
 type ProcInfo struct {
@@ -180,6 +776,8 @@ type ProcInfo struct {
 	PName     string
 	PPath     string
 	Deny      bool
+	DenyDir   bool // Deny was raised for a directory listing rather than a file open, see fileEventDirDenied
+	DryRun    bool // Deny was a dry-run would-be denial, see FsmonConfig.DryRunEnforce; access was allowed through
 	InProfile bool
 }
 
@@ -199,15 +797,49 @@ type IMonProbeData struct {
 }
 
 type FmonProbeData struct {
-	NFileEvents int
-	NGroups     int
-	Fan         FaMonProbeData
-	Ino         IMonProbeData
+	NFileEvents      int
+	NGroups          int
+	NPausedGroups    int   // see FileWatch.PauseContainer
+	NDroppedEvents   int64 // see FileMonitorConfig.MaxPendingEvents
+	NTruncatedGroups int   // see FileMonitorConfig.MaxWatchesPerContainer
+	Fan              FaMonProbeData
+	Ino              IMonProbeData
 }
 
 type FsmonConfig struct {
 	Profile *share.CLUSFileMonitorProfile
 	Rule    *share.CLUSFileAccessRule
+	// ExcludePaths lists regex patterns matched against the container-relative
+	// path (as returned by ParseContainerFilePath). Any match never generates a
+	// monitor event. Patterns that fail to compile are logged once and skipped.
+	ExcludePaths []string
+	// SensitiveMounts lists container-relative paths (or path prefixes) known
+	// to be bind-mounted sensitive host resources -- e.g. the Docker or
+	// containerd socket, or /proc or /sys -- for this container. Events under
+	// any of these paths are flagged with critical severity, since a write
+	// there is a strong signal of a container breakout attempt.
+	SensitiveMounts []string
+	// WalkTimeout overrides rootIterTimeout for the root-filesystem walk
+	// StartWatch performs to seed the initial file list, e.g. to give a
+	// container with an unusually large or slow (network) filesystem more
+	// time than the 16s default. Zero keeps the default.
+	WalkTimeout time.Duration
+	// DryRunEnforce lets an operator validate an Enforce profile before
+	// trusting it to actually block: the group is set up exactly as it would
+	// be for real enforcement, but fanotify never denies the underlying
+	// access -- a would-be denial is only reported, see ProcInfo.DryRun and
+	// policyActionWouldDeny. Has no effect outside PolicyModeEnforce.
+	DryRunEnforce bool
+}
+
+// StartWatchReq is one container's arguments to StartWatch, batched up for
+// StartWatchBatch.
+type StartWatchReq struct {
+	ID            string
+	RootPid       int
+	Conf          *FsmonConfig
+	CapBlock      bool
+	BNeuvectorSvc bool
 }
 
 type FileMonitorConfig struct {
@@ -220,6 +852,242 @@ type FileMonitorConfig struct {
 	SendReport     SendAggregateReportCallback
 	SendAccessRule SendFileAccessRuleCallback
 	EstRule        EstimateRuleSrcCallback
+	// ReportInterval controls how often aggregated file events are sent to the
+	// controller. Defaults to defaultReportInterval when zero.
+	ReportInterval time.Duration
+	// LearnInterval controls how often learned access rules are flushed.
+	// Defaults to defaultLearnInterval when zero.
+	LearnInterval time.Duration
+	// HashAlgorithm selects the baseline hash algorithm ("md5" or "sha256").
+	// Zero value preserves the original behavior. See osutil.SetFileHashAlgorithm.
+	HashAlgorithm string
+	// ThrottleFactor flags a path's event as anomalous once its rate within
+	// ThrottleWindow exceeds the learned baseline by this factor. Zero disables.
+	ThrottleFactor float64
+	// ThrottleWindow is the rate window for ThrottleFactor's baseline.
+	// Defaults to defaultThrottleWindow when zero.
+	ThrottleWindow time.Duration
+	// EventIDBucket rounds the timestamp used in MonitorMessage.EventID, so
+	// repeat deliveries within a bucket hash to the same ID. Defaults to
+	// defaultEventIDBucket when zero.
+	EventIDBucket time.Duration
+	// MaxReportsPerContainer caps how many monitor reports a single
+	// container may generate within ThrottleWindow. Once the cap is hit,
+	// further events in the same window are withheld and coalesced into a
+	// single "suppressed" summary message reported at the start of the next
+	// window. Zero disables the limit.
+	MaxReportsPerContainer int
+	// EventSampleRate optionally samples down noisy, low-severity event
+	// types instead of reporting every occurrence, e.g.
+	// {fileEventAccessed: 100} to report roughly 1-in-100 file-accessed
+	// events within a single ThrottleWindow, coalescing the rest into a
+	// periodic "suppressed" summary message like MaxReportsPerContainer
+	// does. Keyed by the fileEvent* event type; a missing entry, or one
+	// <= 1, means never sample that type. Critical events and denials
+	// (share.PolicyActionDeny / the fsmon-internal would-deny action) are
+	// always reported regardless of this setting. See allowSample.
+	EventSampleRate map[uint32]int
+	// SendReportBatch, when set, enables batched delivery: non-critical,
+	// non-deny messages are accumulated and delivered together once
+	// BatchMaxSize messages have queued or a timer that fires every
+	// BatchFlushInterval next ticks, whichever comes first. Critical and
+	// deny messages always bypass batching and go out immediately via
+	// SendReport, for low latency on events that matter most. Leave nil to
+	// disable batching and send every message individually, as before.
+	SendReportBatch SendBatchReportCallback
+	// BatchMaxSize caps the number of messages held in a batch before it is
+	// flushed early. Defaults to defaultBatchMaxSize when zero.
+	BatchMaxSize int
+	// BatchFlushInterval bounds how long a non-empty batch may sit before
+	// being flushed regardless of size. Defaults to defaultBatchFlushInterval
+	// when zero.
+	BatchFlushInterval time.Duration
+	// DisableInotify skips opening Inotify, e.g. on hosts where
+	// max_user_watches is exhausted. Directory-creation fidelity is reduced,
+	// since fanotify alone cannot see new entries in an already-watched
+	// directory as promptly. Cannot be set together with DisableFanotify.
+	DisableInotify bool
+	// DisableFanotify skips opening FaNotify. Cannot be set together with
+	// DisableInotify -- at least one notifier is required for the file
+	// monitor to do anything.
+	DisableFanotify bool
+	// MaxWatches caps total files/directories watched across all containers,
+	// keeping mark counts under kernel limits like fs.inotify.max_user_watches.
+	// Once exhausted, addFile/addDir admit by priority (see watchPriority) and
+	// skip the rest with a warning. Zero disables the limit.
+	MaxWatches int
+	// MaxWatchesPerContainer caps how many files/directories a single
+	// container may have watched, independent of MaxWatches, so one
+	// container with a huge recursive tree (e.g. under /usr/bin) can't
+	// consume the node-wide fanotify mark budget and starve every other
+	// container. Once a container hits the cap, further additions for it
+	// are skipped (a single warning event is logged for the transition
+	// into truncation, not per skipped file) and it's flagged in
+	// GetProbeData. Zero disables the limit.
+	MaxWatchesPerContainer int
+	// WalkAcquireTimeout bounds how long a StartWatch call will wait for a
+	// free walker slot (see walkerLimiter) before giving up on that path with
+	// a logged timeout, instead of queuing indefinitely behind other
+	// containers' rootfs walks. Defaults to defaultWalkAcquireTimeout when
+	// zero.
+	WalkAcquireTimeout time.Duration
+	// NetworkActivityHint, when set, is consulted in sendMsg to flag file
+	// events that closely follow outbound network activity from the same
+	// container -- a possible download-and-execute pattern. fsmon has no
+	// network visibility of its own; this lets a caller that does supply
+	// that signal without fsmon owning any network logic. See
+	// networkCorrelationWindow.
+	NetworkActivityHint NetworkActivityHint
+	// RawEventCallback, when set, is invoked synchronously in cbNotify for
+	// every raw event as it arrives, before aggregation into fileEvents --
+	// e.g. to stream events to an external SIEM in real time. It's called
+	// without holding FileWatch's internal lock, so a slow callback delays
+	// only the notifier goroutine that observed the event, not other
+	// containers' event processing. Nil-safe: leave nil to disable.
+	RawEventCallback RawEventCallback
+	// MaxPendingEvents caps how many distinct paths may accumulate in
+	// w.fileEvents between HandleWatchedFiles drains. Once the cap is
+	// reached, cbNotify drops events for any path not already pending --
+	// keeping the currently tracked distinct paths intact rather than
+	// letting a flood on one path crowd out visibility into others -- and
+	// counts the drop, reported via GetProbeData.NDroppedEvents. Zero
+	// disables the limit.
+	MaxPendingEvents int
+	// PathSeverity overrides how sendMsg computes MonitorMessage.Severity.
+	// Nil uses defaultPathSeverity.
+	PathSeverity PathSeverityCallback
+	// CaptureDiff enables unified-diff snippets on MonitorMessage.Diff for
+	// modified files at or under DiffMaxSize that are detected as text.
+	// Binary files, and files over the size limit, never get a diff. See
+	// handleFileEvents.
+	CaptureDiff bool
+	// DiffMaxSize caps the size of a file CaptureDiff will diff. Defaults to
+	// defaultDiffMaxSize when zero.
+	DiffMaxSize int64
+	// HashCacheTTL enables a short-lived per-path cache of computed file
+	// hashes, consulted by handleFileEvents before calling
+	// osutil.GetFileHash again. An entry is keyed by path and invalidated as
+	// soon as the file's size or mtime no longer matches what was hashed, so
+	// it only saves I/O on repeated modify/access events that didn't
+	// actually change the file's content -- e.g. /etc/hosts churn. Zero
+	// disables the cache, this package's historical behavior.
+	HashCacheTTL time.Duration
+	// HashCacheSize bounds how many paths HashCacheTTL's cache holds at
+	// once, evicting arbitrarily (not LRU) once full. Defaults to
+	// defaultHashCacheSize when HashCacheTTL is set and this is zero.
+	HashCacheSize int
+	// Aggregation controls how repeated events on the same path within a
+	// drain interval are merged into reported messages. Defaults to
+	// AggregationDefault, fsmon's historical behavior. See AggregationMode.
+	Aggregation AggregationMode
+	// CoalescePackageEvents enables summarizing bursts of events under a
+	// package manager's database directory (/var/lib/dpkg, /var/lib/rpm,
+	// /lib/apk) into a single "package database modified" MonitorMessage per
+	// packageCoalesceWindow, instead of one report per file touched during
+	// an install or upgrade. See recordPackageActivity.
+	CoalescePackageEvents bool
+	// RuntimeManagedPaths lists container-relative paths the container
+	// runtime injects or rewrites itself -- e.g. on IP or DNS changes --
+	// rather than the image or workload doing so, and so shouldn't be
+	// learned or reported as file events. Defaults to
+	// defaultRuntimeManagedPaths when nil. See isRunTimeAddedFile.
+	RuntimeManagedPaths []string
+	// DetectFileChurn distinguishes a path in a watched recursive directory
+	// that was created and then removed within the same aggregation window
+	// (e.g. a build tool's temp files) from an ordinary deletion, reporting
+	// fileEventTransient instead of fileEventRemoved. False preserves this
+	// package's historical behavior of always reporting the net "removed"
+	// state as a plain deletion.
+	DetectFileChurn bool
+	// SuppressFileChurn drops a churn event identified by DetectFileChurn
+	// entirely instead of reporting it as fileEventTransient. Ignored unless
+	// DetectFileChurn is also set.
+	SuppressFileChurn bool
+	// ImageLookup, when set, is consulted once per report in sendMsg to
+	// populate MonitorMessage.Image/ContainerName, e.g. so a SOC analyst
+	// sees the offending container's image and name, not just its ID. Nil
+	// leaves both fields empty.
+	ImageLookup ImageLookupCallback
+	// BaselineReadyCallback, when set, is invoked at the end of StartWatch
+	// after StartMonitor, once a container's initial getCoreFile walk and
+	// watch setup completes, so a caller can mark the container "protected"
+	// in a dashboard. fileCount is the number of files/directories watched;
+	// it fires with fileCount zero when the walk found nothing to watch.
+	BaselineReadyCallback BaselineReadyCallback
+	// CreateDebounce holds a lone create event, in case a move/rename or
+	// delete supersedes it shortly after (which is reported immediately
+	// instead) -- e.g. a temp file renamed over its target. Rounded up to
+	// the nearest ReportInterval tick. Zero disables debouncing.
+	CreateDebounce time.Duration
+	// PermDecisionTimeout bounds how long FaNotify's block-access decision may
+	// run before the kernel is answered with an allow default. A late result
+	// is still reported, as fileEventDenyTimeout. Defaults to
+	// defaultPermDecisionTimeout when zero. See FaNotify.SetDecisionTimeout.
+	PermDecisionTimeout time.Duration
+}
+
+// defaultRuntimeManagedPaths is used when FileMonitorConfig.RuntimeManagedPaths
+// is nil, matching this package's historical hardcoded list.
+var defaultRuntimeManagedPaths = []string{
+	"/etc/hosts",
+	"/etc/hostname",
+	"/etc/resolv.conf",
+}
+
+// networkCorrelationWindow bounds how recently a container must have had
+// outbound network activity, per NetworkActivityHint, for a file event to be
+// flagged as network-correlated in MonitorMessage.NetworkCorrelated.
+const networkCorrelationWindow = time.Minute
+
+const (
+	defaultReportInterval     = time.Second * 4
+	defaultLearnInterval      = time.Second * 10
+	defaultEventIDBucket      = time.Minute
+	defaultBatchMaxSize       = 32
+	defaultBatchFlushInterval = time.Second * 2
+	// defaultWalkerConcurrency caps how many rootfs walks (see
+	// FileWatch.walkerLimiter) may run at once across all containers, so a
+	// burst of concurrent StartWatch calls can't pile up unboundedly many
+	// external pathwalker processes.
+	defaultWalkerConcurrency  = 4
+	defaultWalkAcquireTimeout = time.Second * 30
+	// defaultDiffMaxSize bounds the file size FileMonitorConfig.CaptureDiff
+	// will diff, keeping a diff snippet cheap to compute and to carry in a
+	// MonitorMessage.
+	defaultDiffMaxSize = 64 * 1024
+	// defaultHashCacheSize bounds FileMonitorConfig.HashCacheTTL's cache when
+	// HashCacheSize isn't set.
+	defaultHashCacheSize = 1024
+	// packageCoalesceWindow bounds how long recordPackageActivity accumulates
+	// package-manager-path events for a container before summarizing them
+	// into a single MonitorMessage, see FileMonitorConfig.CoalescePackageEvents.
+	packageCoalesceWindow = time.Second * 5
+	// defaultPermDecisionTimeout is used when FileMonitorConfig.PermDecisionTimeout
+	// is zero. See FaNotify.SetDecisionTimeout.
+	defaultPermDecisionTimeout = time.Second * 2
+)
+
+// packageManagerDirs are the package database directories recordPackageActivity
+// watches for, in addition to osutil.IsPackageLib's specific marker files.
+var packageManagerDirs = []string{
+	"/var/lib/dpkg",
+	"/var/lib/rpm",
+	"/lib/apk",
+	"/var/lib/apk",
+}
+
+// isPackageManagerPath reports whether path is a package manager database
+// file or lives under one of packageManagerDirs.
+func isPackageManagerPath(path string) bool {
+	if osutil.IsPackageLib(path) {
+		return true
+	}
+	for _, dir := range packageManagerDirs {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
 }
 
 func NewFileWatcher(config *FileMonitorConfig) (*FileWatch, error) {
@@ -227,19 +1095,121 @@ func NewFileWatcher(config *FileMonitorConfig) (*FileWatch, error) {
 	mLog.Out = os.Stdout
 	mLog.Level = log.InfoLevel
 	mLog.Formatter = &utils.LogFormatter{Module: "AGT"}
+	faLog.Out = os.Stdout
+	faLog.Level = log.InfoLevel
+	faLog.Formatter = &utils.LogFormatter{Module: "AGT"}
+	inLog.Out = os.Stdout
+	inLog.Level = log.InfoLevel
+	inLog.Formatter = &utils.LogFormatter{Module: "AGT"}
 	if config.EnableTrace {
 		mLog.SetLevel(log.DebugLevel)
+		faLog.SetLevel(log.DebugLevel)
+		inLog.SetLevel(log.DebugLevel)
+	}
+	osutil.SetFileHashAlgorithm(config.HashAlgorithm)
+
+	reportInterval := config.ReportInterval
+	if reportInterval <= 0 {
+		reportInterval = defaultReportInterval
+	}
+	createDebounceTicks := 0
+	if config.CreateDebounce > 0 {
+		createDebounceTicks = int((config.CreateDebounce + reportInterval - 1) / reportInterval)
+		if createDebounceTicks < 1 {
+			createDebounceTicks = 1
+		}
+	}
+	learnInterval := config.LearnInterval
+	if learnInterval <= 0 {
+		learnInterval = defaultLearnInterval
+	}
+	throttleWindow := config.ThrottleWindow
+	if throttleWindow <= 0 {
+		throttleWindow = defaultThrottleWindow
+	}
+	eventIDBucket := config.EventIDBucket
+	if eventIDBucket <= 0 {
+		eventIDBucket = defaultEventIDBucket
+	}
+	batchMaxSize := config.BatchMaxSize
+	if batchMaxSize <= 0 {
+		batchMaxSize = defaultBatchMaxSize
+	}
+	batchFlushInterval := config.BatchFlushInterval
+	if batchFlushInterval <= 0 {
+		batchFlushInterval = defaultBatchFlushInterval
+	}
+	walkAcquireTimeout := config.WalkAcquireTimeout
+	if walkAcquireTimeout <= 0 {
+		walkAcquireTimeout = defaultWalkAcquireTimeout
+	}
+	diffMaxSize := config.DiffMaxSize
+	if diffMaxSize <= 0 {
+		diffMaxSize = defaultDiffMaxSize
+	}
+	runtimeManagedPaths := config.RuntimeManagedPaths
+	if runtimeManagedPaths == nil {
+		runtimeManagedPaths = defaultRuntimeManagedPaths
+	}
+	hashCacheSize := config.HashCacheSize
+	if hashCacheSize <= 0 {
+		hashCacheSize = defaultHashCacheSize
 	}
 
 	fw := &FileWatch{
-		bEnable:    config.ProfileEnable,
-		aufs:       config.IsAufs,
-		fileEvents: make(map[string]*fileMod),
-		groups:     make(map[int]*groupInfo),
-		sendrpt:    config.SendReport,
-		sendRule:   config.SendAccessRule,
-		estRuleSrc: config.EstRule,
-		walkerTask: config.WalkerTask,
+		bEnable:                config.ProfileEnable,
+		aufs:                   config.IsAufs,
+		fileEvents:             make(map[string]*fileMod),
+		groups:                 make(map[int]*groupInfo),
+		sendrpt:                config.SendReport,
+		sendRule:               config.SendAccessRule,
+		estRuleSrc:             config.EstRule,
+		walkerTask:             config.WalkerTask,
+		reportInterval:         reportInterval,
+		learnInterval:          learnInterval,
+		tickerReset:            make(chan bool, 1),
+		done:                   make(chan struct{}),
+		rateStats:              make(map[string]*pathRateStat),
+		throttleFactor:         config.ThrottleFactor,
+		throttleWindow:         throttleWindow,
+		eventIDBucket:          eventIDBucket,
+		reportBuckets:          make(map[string]*containerReportBucket),
+		maxReports:             config.MaxReportsPerContainer,
+		eventSampleRate:        config.EventSampleRate,
+		sampleBuckets:          make(map[uint32]*eventSampleBucket),
+		sendBatch:              config.SendReportBatch,
+		batchMaxSize:           batchMaxSize,
+		batchFlushInterval:     batchFlushInterval,
+		watchBudget:            config.MaxWatches,
+		topPathCounts:          make(map[string]int64),
+		maxWatchesPerContainer: config.MaxWatchesPerContainer,
+		containerWatchCount:    make(map[string]int),
+		containerTruncated:     make(map[string]bool),
+		walkerLimiter:          semaphore.NewWeighted(defaultWalkerConcurrency),
+		walkAcquireTimeout:     walkAcquireTimeout,
+		networkActivityHint:    config.NetworkActivityHint,
+		rawEventCallback:       config.RawEventCallback,
+		maxPendingEvents:       config.MaxPendingEvents,
+		pathSeverity:           config.PathSeverity,
+		captureDiff:            config.CaptureDiff,
+		diffMaxSize:            diffMaxSize,
+		diffCache:              make(map[string][]byte),
+		hashCacheTTL:           config.HashCacheTTL,
+		hashCacheSize:          hashCacheSize,
+		hashCache:              make(map[string]*hashCacheEntry),
+		lastResync:             make(map[int]time.Time),
+		aggregationMode:        config.Aggregation,
+		coalescePackages:       config.CoalescePackageEvents,
+		pkgActivity:            make(map[string]*pkgActivityBucket),
+		pidLookup:              config.PidLookup,
+		upperDirs:              make(map[string]int),
+		filterEvents:           make(map[int]map[string]int64),
+		runtimeManagedPaths:    runtimeManagedPaths,
+		detectFileChurn:        config.DetectFileChurn,
+		suppressFileChurn:      config.SuppressFileChurn,
+		imageLookup:            config.ImageLookup,
+		baselineReady:          config.BaselineReadyCallback,
+		createDebounceTicks:    createDebounceTicks,
 	}
 
 	if !fw.bEnable {
@@ -248,61 +1218,238 @@ func NewFileWatcher(config *FileMonitorConfig) (*FileWatch, error) {
 		return fw, nil
 	}
 
-	n, err := NewFaNotify(config.EndChan, config.PidLookup, global.SYS)
-	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Error("Open fanotify fail")
-		return nil, err
+	if config.DisableFanotify && config.DisableInotify {
+		return nil, errors.New("fsmon: DisableFanotify and DisableInotify cannot both be set, at least one notifier is required")
 	}
 
-	ni, err := NewInotify()
-	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Error("Open inotify fail")
-		return nil, err
+	if !config.DisableFanotify {
+		n, err := NewFaNotify(config.EndChan, config.PidLookup, global.SYS)
+		if err != nil {
+			log.WithFields(log.Fields{"error": err}).Error("Open fanotify fail")
+			return nil, err
+		}
+		permDecisionTimeout := config.PermDecisionTimeout
+		if permDecisionTimeout <= 0 {
+			permDecisionTimeout = defaultPermDecisionTimeout
+		}
+		n.SetDecisionTimeout(permDecisionTimeout)
+		go n.MonitorFileEvents()
+		fw.fanotifier = n
 	}
 
-	go n.MonitorFileEvents()
-	go ni.MonitorFileEvents()
-
-	fw.fanotifier = n
-	fw.inotifier = ni
+	if !config.DisableInotify {
+		ni, err := NewInotify()
+		if err != nil {
+			log.WithFields(log.Fields{"error": err}).Error("Open inotify fail")
+			return nil, err
+		}
+		ni.SetOverflowCallback(fw.handleQueueOverflow)
+		go ni.MonitorFileEvents()
+		fw.inotifier = ni
+	}
 
 	go fw.loop()
 	return fw, nil
 }
 
-func (w *FileWatch) sendMsg(cid string, path string, event uint32, pInfo []*ProcInfo, mode string) {
+// anomalousRateMarker is appended to a message's event text when the path's
+// observed event rate exceeds its learned baseline by the configured factor.
+const anomalousRateMarker = " (anomalous activity rate)"
+
+// criticalMountMarker is appended to a message's event text when the path is
+// a sensitive mount, see FsmonConfig.SensitiveMounts.
+const criticalMountMarker = " (critical: sensitive mount)"
+
+// policyActionWouldDeny is MonitorMessage.Action for a denial that
+// FsmonConfig.DryRunEnforce turned into a report-only event -- fanotify let
+// the access through instead of blocking it, see ProcInfo.DryRun. This is
+// deliberately not one of the share.PolicyAction* constants: it's fsmon's own
+// dry-run bookkeeping, not a real policy decision other components need to
+// recognize.
+const policyActionWouldDeny = "would-deny"
+
+// computeEventID derives a stable content-addressed ID for a monitor event
+// from its salient fields, so the same logical event -- e.g. redelivered by
+// a retrying downstream consumer -- produces the same ID across components.
+// The timestamp is bucketed to w.eventIDBucket granularity so events that
+// are logically the same but occur moments apart still collapse to one ID,
+// while distinct occurrences in different buckets still get distinct IDs.
+func (w *FileWatch) computeEventID(cid, path string, event uint32, procPath string) string {
+	bucket := time.Now().Truncate(w.eventIDBucket).Unix()
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%d|%s|%d", cid, path, event, procPath, bucket)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// report delivers msg and counts it toward totalReportsSent. Critical and
+// deny messages, and messages when batching is disabled, go out immediately
+// via sendrpt; everything else is queued for batched delivery, see
+// queueBatch.
+func (w *FileWatch) report(msg *MonitorMessage) {
+	atomic.AddInt64(&w.totalReportsSent, 1)
+	if w.sendBatch == nil || msg.Critical || msg.Action == share.PolicyActionDeny || msg.Action == policyActionWouldDeny {
+		w.sendrpt(msg)
+		return
+	}
+	w.queueBatch(msg)
+}
+
+// queueBatch appends msg to the in-flight batch, flushing immediately if
+// that fills it to batchMaxSize. A partially-filled batch is flushed later by
+// loop's batch ticker, which fires every batchFlushInterval independently of
+// when the batch's first message was queued.
+func (w *FileWatch) queueBatch(msg *MonitorMessage) {
+	w.batchMu.Lock()
+	if len(w.batchBuf) == 0 {
+		w.batchFirstAt = time.Now()
+	}
+	w.batchBuf = append(w.batchBuf, msg)
+	full := len(w.batchBuf) >= w.batchMaxSize
+	w.batchMu.Unlock()
+
+	if full {
+		w.flushBatch()
+	}
+}
+
+// flushBatch delivers and clears the in-flight batch, if any. Safe to call
+// unconditionally on a timer, since an empty batch is a no-op.
+func (w *FileWatch) flushBatch() {
+	w.batchMu.Lock()
+	if len(w.batchBuf) == 0 {
+		w.batchMu.Unlock()
+		return
+	}
+	batch := w.batchBuf
+	w.batchBuf = nil
+	w.batchMu.Unlock()
+
+	w.sendBatch(batch)
+}
+
+// attributeByPidLookup is sendMsg's fallback for an event with no process
+// info at all -- e.g. inotify, which doesn't report the writer -- using the
+// PidLookup callback to attribute the event to the container's main process
+// on a best-effort basis. It returns nil if PidLookup isn't configured or
+// doesn't know about rootPid.
+func (w *FileWatch) attributeByPidLookup(rootPid int) *ProcInfo {
+	if w.pidLookup == nil {
+		return nil
+	}
+	return w.pidLookup(rootPid)
+}
+
+// resolveImage looks up cid's image reference and container name via
+// imageLookup, tolerating a nil callback by returning empty strings. See
+// FileMonitorConfig.ImageLookup.
+func (w *FileWatch) resolveImage(cid string) (image, name string) {
+	if w.imageLookup == nil {
+		return "", ""
+	}
+	return w.imageLookup(cid)
+}
+
+func (w *FileWatch) sendMsg(rootPid int, cid string, path string, event uint32, pInfo []*ProcInfo, mode string, anomalous bool, caps string, critical bool, captureFDs bool, modeChanged bool, ownerChanged bool, symlinkTarget string, diff string) {
+	netCorrelated := false
+	if w.networkActivityHint != nil {
+		if lastConn := w.networkActivityHint(rootPid); !lastConn.IsZero() {
+			netCorrelated = time.Since(lastConn) <= networkCorrelationWindow
+		}
+	}
+
+	severityFn := w.pathSeverity
+	if severityFn == nil {
+		severityFn = defaultPathSeverity
+	}
+	severity := severityFn(path, event, critical)
+
 	eventMsg, ok := fileEventMsg[event]
 	if !ok {
 		log.WithFields(log.Fields{"path": path, "event": eventMsg}).Error("FMON: Unkown event")
 		return
 	}
+	if anomalous {
+		eventMsg += anomalousRateMarker
+	}
+	if event == fileEventCapability && caps != "" {
+		eventMsg += fmt.Sprintf(" (capabilities: %s)", caps)
+	}
+	if event == fileEventTypeChanged && symlinkTarget != "" {
+		eventMsg += fmt.Sprintf(" (new target: %s)", symlinkTarget)
+	}
+	if critical {
+		eventMsg += criticalMountMarker
+	}
 
 	log.WithFields(log.Fields{"path": path, "event": eventMsg, "proc": pInfo}).Debug("FMON:")
 
+	image, containerName := w.resolveImage(cid)
+
 	if pInfo == nil {
 		msg := MonitorMessage{
-			ID:      cid,
-			Path:    path,
-			Group:   w.estRuleSrc(cid, path, event == fileEventDenied),
-			Package: osutil.IsPackageLib(path),
-			Msg:     eventMsg,
-			Action:  share.PolicyActionViolate,
+			ID:                cid,
+			EventID:           w.computeEventID(cid, path, event, ""),
+			Path:              path,
+			Group:             w.estRuleSrc(cid, path, isDeniedEvent(event)),
+			Package:           osutil.IsPackageLib(path),
+			Msg:               eventMsg,
+			Action:            share.PolicyActionViolate,
+			Anomalous:         anomalous,
+			Critical:          critical,
+			NetworkCorrelated: netCorrelated,
+			Severity:          severity,
+			Diff:              diff,
+			Image:             image,
+			ContainerName:     containerName,
+		}
+		if attributed := w.attributeByPidLookup(rootPid); attributed != nil {
+			msg.ProcName = attributed.Name
+			msg.ProcPath = attributed.Path
+			msg.ProcPid = attributed.Pid
+			msg.ProcEUid = attributed.EUid
+			msg.ProcEUser = attributed.EUser
+			msg.AttributionConfidence = "low"
 		}
 
-		w.sendrpt(&msg)
-		//	log.WithFields(log.Fields{"file": path, "container": cid}).Debug("File modified catched")
+		allow, suppressedMsg := w.allowReport(cid)
+		if suppressedMsg != nil {
+			w.report(suppressedMsg)
+		}
+		sampledIn, sampledOutMsg := w.allowSample(cid, event, critical, msg.Action)
+		if sampledOutMsg != nil {
+			w.report(sampledOutMsg)
+		}
+		if allow && sampledIn {
+			w.report(&msg)
+			w.recordTopPath(path)
+			//	log.WithFields(log.Fields{"file": path, "container": cid}).Debug("File modified catched")
+		}
 		return
 	}
 	// check whether the file was modified by same process.
 	for i, pi := range pInfo {
 		if i == 0 || !reflect.DeepEqual(pInfo[i-1], pi) {
+			procPath := ""
+			if pi != nil {
+				procPath = pi.Path
+			}
 			msg := MonitorMessage{
-				ID:      cid,
-				Path:    path,
-				Group:   w.estRuleSrc(cid, path, event == fileEventDenied),
-				Package: osutil.IsPackageLib(path),
-				Msg:     eventMsg,
-				Action:  share.PolicyActionViolate,
+				ID:                cid,
+				EventID:           w.computeEventID(cid, path, event, procPath),
+				Path:              path,
+				Group:             w.estRuleSrc(cid, path, isDeniedEvent(event)),
+				Package:           osutil.IsPackageLib(path),
+				Msg:               eventMsg,
+				Action:            share.PolicyActionViolate,
+				Anomalous:         anomalous,
+				Critical:          critical,
+				ModeChanged:       modeChanged,
+				OwnerChanged:      ownerChanged,
+				NetworkCorrelated: netCorrelated,
+				Severity:          severity,
+				Diff:              diff,
+				Image:             image,
+				ContainerName:     containerName,
 			}
 			if pi != nil {
 				msg.ProcName = pi.Name
@@ -315,32 +1462,187 @@ func (w *FileWatch) sendMsg(cid string, path string, event uint32, pInfo []*Proc
 				msg.ProcPName = pi.PName
 				msg.ProcPPath = pi.PPath
 				if pi.Deny {
-					msg.Action = share.PolicyActionDeny
-					msg.Msg = fileEventMsg[fileEventDenied]
+					if pi.DryRun {
+						msg.Action = policyActionWouldDeny
+					} else {
+						msg.Action = share.PolicyActionDeny
+					}
+					if pi.DenyDir {
+						msg.Msg = fileEventMsg[fileEventDirDenied]
+					} else {
+						msg.Msg = fileEventMsg[fileEventDenied]
+					}
+				}
+				if captureFDs {
+					msg.OpenFDs = captureOpenFDs(pi.Pid)
 				}
 			}
 
-			w.sendrpt(&msg)
-			//	log.WithFields(log.Fields{"file": path, "container": cid}).Debug("File modified catched")
+			allow, suppressedMsg := w.allowReport(cid)
+			if suppressedMsg != nil {
+				w.report(suppressedMsg)
+			}
+			sampledIn, sampledOutMsg := w.allowSample(cid, event, critical, msg.Action)
+			if sampledOutMsg != nil {
+				w.report(sampledOutMsg)
+			}
+			if allow && sampledIn {
+				w.report(&msg)
+				w.recordTopPath(path)
+				//	log.WithFields(log.Fields{"file": path, "container": cid}).Debug("File modified catched")
+			}
 		} else {
 			log.WithFields(log.Fields{"file": path, "container": cid, "pInfo": pi}).Debug("duplicate File modified")
 		}
 	}
 }
 
+// PathCount is one entry of FileWatch.TopPaths.
+type PathCount struct {
+	Path  string
+	Count int64
+}
+
+// recordTopPath tallies path for TopPaths, called once per message sendMsg
+// actually reports (not for reports withheld by allowReport/allowSample).
+func (w *FileWatch) recordTopPath(path string) {
+	w.topPathMu.Lock()
+	w.topPathCounts[path]++
+	w.topPathMu.Unlock()
+}
+
+// TopPaths returns the n most frequently reported container-relative paths
+// since the last ResetTopPaths (or since startup), sorted by count
+// descending, ties broken by path for stable output. Helps an operator spot
+// a misconfigured recursive filter generating most of a profile's noise.
+func (w *FileWatch) TopPaths(n int) []PathCount {
+	w.topPathMu.Lock()
+	counts := make([]PathCount, 0, len(w.topPathCounts))
+	for path, count := range w.topPathCounts {
+		counts = append(counts, PathCount{Path: path, Count: count})
+	}
+	w.topPathMu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Path < counts[j].Path
+	})
+	if n >= 0 && n < len(counts) {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// ResetTopPaths clears TopPaths' accumulated counts, so a controller can
+// sample a fresh window periodically instead of an ever-growing total.
+func (w *FileWatch) ResetTopPaths() {
+	w.topPathMu.Lock()
+	w.topPathCounts = make(map[string]int64)
+	w.topPathMu.Unlock()
+}
+
+// SetIntervals retunes the report/learn aggregation tickers at runtime without
+// restarting the watcher. A zero duration leaves the corresponding interval
+// unchanged.
+func (w *FileWatch) SetIntervals(report, learn time.Duration) {
+	w.mux.Lock()
+	if report > 0 {
+		w.reportInterval = report
+	}
+	if learn > 0 {
+		w.learnInterval = learn
+	}
+	w.mux.Unlock()
+
+	select {
+	case w.tickerReset <- true:
+	default:
+	}
+}
+
+// tickerJitterFraction bounds the randomized startup jitter applied to
+// msgTicker/learnTicker, so agents that all start around the same time (e.g.
+// after a rolling restart) don't all fire in lockstep and flood the
+// controller every reportInterval/learnInterval.
+const tickerJitterFraction = 0.2
+
+// jitterInterval returns d scaled by a random factor within
+// ±tickerJitterFraction, or d unchanged if d isn't positive.
+func jitterInterval(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	factor := 1 - tickerJitterFraction + rand.Float64()*2*tickerJitterFraction
+	return time.Duration(float64(d) * factor)
+}
+
 func (w *FileWatch) loop() {
-	msgTicker := time.Tick(time.Second * 4)
-	// every 10s send learning rules to controller
-	learnTicker := time.Tick(time.Second * 10)
+	w.mux.Lock()
+	msgTicker := time.NewTicker(jitterInterval(w.reportInterval))
+	learnTicker := time.NewTicker(jitterInterval(w.learnInterval))
+	w.mux.Unlock()
+	defer msgTicker.Stop()
+	defer learnTicker.Stop()
+
+	var batchTicker *time.Ticker
+	var batchTickerC <-chan time.Time
+	if w.sendBatch != nil {
+		batchTicker = time.NewTicker(w.batchFlushInterval)
+		batchTickerC = batchTicker.C
+		defer batchTicker.Stop()
+	}
 
 	for {
 		select {
-		case <-msgTicker:
+		case <-msgTicker.C:
 			w.HandleWatchedFiles()
-		case <-learnTicker:
+		case <-learnTicker.C:
 			w.reportLearningRules()
+		case <-batchTickerC:
+			w.flushBatch()
+		case <-w.tickerReset:
+			w.mux.Lock()
+			report, learn := w.reportInterval, w.learnInterval
+			w.mux.Unlock()
+			msgTicker.Stop()
+			learnTicker.Stop()
+			msgTicker = time.NewTicker(report)
+			learnTicker = time.NewTicker(learn)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// GetLearnedRules returns a snapshot of the not-yet-flushed learned file-access
+// rules for a group, built exactly as reportLearningRules would, but without
+// resetting grp.learnRules.
+func (w *FileWatch) GetLearnedRules(rootPid int) []*share.CLUSFileAccessRuleReq {
+	learnRules := make([]*share.CLUSFileAccessRuleReq, 0)
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	grp, ok := w.groups[rootPid]
+	if !ok {
+		return learnRules
+	}
+
+	for flt, rule := range grp.learnRules {
+		group := grp.profile.Group
+		for itr := range rule.Iter() {
+			prf := itr.(string)
+			rl := &share.CLUSFileAccessRuleReq{
+				GroupName: group,
+				Filter:    flt,
+				Path:      prf,
+			}
+			learnRules = append(learnRules, rl)
 		}
 	}
+	return learnRules
 }
 
 func (w *FileWatch) reportLearningRules() {
@@ -369,7 +1671,7 @@ func (w *FileWatch) reportLearningRules() {
 					learnRules = append(learnRules, rl)
 				}
 			}
-			grp.learnRules = make(map[string]utils.Set)	// reset
+			grp.learnRules = make(map[string]utils.Set) // reset
 		}
 	}
 	w.mux.Unlock()
@@ -395,6 +1697,152 @@ func filterPathMatch(path string, flt share.CLUSFileMonitorFilter) bool {
 	return false
 }
 
+// matchedFilterKey returns the filterIndexKey of the first filter in grp's
+// profile (checking Filters then FiltersCRD) that matches path, or "" if
+// none do. Used by learnFromEvents to attribute a reported event to the
+// filter responsible for it, see GetFilterStats.
+func matchedFilterKey(grp *groupInfo, path string) string {
+	for _, flt := range grp.profile.Filters {
+		if filterPathMatch(path, flt) {
+			return filterIndexKey(flt)
+		}
+	}
+	for _, flt := range grp.profile.FiltersCRD {
+		if filterPathMatch(path, flt) {
+			return filterIndexKey(flt)
+		}
+	}
+	return ""
+}
+
+// filterEUIDMatch reports whether the filter's EUIDs restriction (if any) admits
+// the given process. An empty EUIDs list places no restriction on the filter.
+func filterEUIDMatch(flt share.CLUSFileMonitorFilter, pi *ProcInfo) bool {
+	if len(flt.EUIDs) == 0 || pi == nil {
+		return true
+	}
+	for _, euid := range flt.EUIDs {
+		if euid == pi.EUid {
+			return true
+		}
+	}
+	return false
+}
+
+// filterProcInfoByEUID finds the filter matching path and, if it carries an EUIDs
+// restriction, drops any pInfo entries whose effective UID isn't in the set.
+func filterProcInfoByEUID(grp *groupInfo, path string, pInfo []*ProcInfo) []*ProcInfo {
+	if grp.profile == nil || len(pInfo) == 0 {
+		return pInfo
+	}
+
+	var matched *share.CLUSFileMonitorFilter
+	for i, flt := range grp.profile.Filters {
+		if len(flt.EUIDs) > 0 && filterPathMatch(path, flt) {
+			matched = &grp.profile.Filters[i]
+			break
+		}
+	}
+	if matched == nil {
+		for i, flt := range grp.profile.FiltersCRD {
+			if len(flt.EUIDs) > 0 && filterPathMatch(path, flt) {
+				matched = &grp.profile.FiltersCRD[i]
+				break
+			}
+		}
+	}
+	if matched == nil {
+		return pInfo
+	}
+
+	filtered := make([]*ProcInfo, 0, len(pInfo))
+	for _, pi := range pInfo {
+		if filterEUIDMatch(*matched, pi) {
+			filtered = append(filtered, pi)
+		}
+	}
+	return filtered
+}
+
+// detectNewMountNamespaces reports any process in pInfo running in a mount
+// namespace other than the container's root one that hasn't already been
+// reported for this group, e.g. because it called unshare(CLONE_NEWNS) or
+// joined another namespace via setns(2) -- both are ways a process could try
+// to move file activity outside the container's monitored root namespace.
+// Must be called with w.mux held, since it updates grp.seenMntNs.
+func (w *FileWatch) detectNewMountNamespaces(grp *groupInfo, cid string, pInfo []*ProcInfo) []*MonitorMessage {
+	if grp.rootMntNs == 0 {
+		return nil
+	}
+	var msgs []*MonitorMessage
+	for _, pi := range pInfo {
+		if pi == nil {
+			continue
+		}
+		ns := global.SYS.GetMntNamespaceId(pi.Pid)
+		if ns == 0 || ns == grp.rootMntNs || grp.seenMntNs[ns] {
+			continue
+		}
+		grp.seenMntNs[ns] = true
+		msgs = append(msgs, &MonitorMessage{
+			ID:       cid,
+			EventID:  w.computeEventID(cid, "", fileEventNewMountNS, pi.Path),
+			ProcName: pi.Name,
+			ProcPath: pi.Path,
+			ProcPid:  pi.Pid,
+			Msg:      fmt.Sprintf("New mount namespace created by process %s (pid=%d)", pi.Name, pi.Pid),
+			Action:   share.PolicyActionViolate,
+			StartAt:  time.Now(),
+		})
+	}
+	return msgs
+}
+
+// filterCaptureOpenFDs reports whether the filter matching path has
+// CaptureOpenFDs enabled.
+func filterCaptureOpenFDs(grp *groupInfo, path string) bool {
+	if grp.profile == nil {
+		return false
+	}
+	for _, flt := range grp.profile.Filters {
+		if filterPathMatch(path, flt) {
+			return flt.CaptureOpenFDs
+		}
+	}
+	for _, flt := range grp.profile.FiltersCRD {
+		if filterPathMatch(path, flt) {
+			return flt.CaptureOpenFDs
+		}
+	}
+	return false
+}
+
+// maxCapturedOpenFDs bounds how many of a process's open file descriptors are
+// captured for CLUSFileMonitorFilter.CaptureOpenFDs, so a process with
+// thousands of open files doesn't blow up message size.
+const maxCapturedOpenFDs = 32
+
+// captureOpenFDs snapshots up to maxCapturedOpenFDs of pid's currently open
+// file paths from /proc/<pid>/fd. Errors reading an individual fd's target
+// are silently skipped; a fully unreadable directory yields a nil slice.
+func captureOpenFDs(pid int) []string {
+	dir := fmt.Sprintf("/proc/%d/fd", pid)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	paths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if len(paths) >= maxCapturedOpenFDs {
+			break
+		}
+		if target, err := os.Readlink(filepath.Join(dir, e.Name())); err == nil {
+			paths = append(paths, target)
+		}
+	}
+	return paths
+}
+
 func addLearnedRules(grp *groupInfo, flt share.CLUSFileMonitorFilter, pInfo []*ProcInfo) {
 	index := filterIndexKey(flt)
 	if applyRules, ok := grp.applyRules[index]; ok {
@@ -412,14 +1860,14 @@ func addLearnedRules(grp *groupInfo, flt share.CLUSFileMonitorFilter, pInfo []*P
 		}
 
 		if learnRules.Cardinality() > 0 {
-			grp.learnRules[index] = learnRules	// update grp
+			grp.learnRules[index] = learnRules // update grp
 		}
 	} else {
 		log.WithFields(log.Fields{"index": index}).Debug("FMON: no access rules")
 	}
 }
 
-func (w *FileWatch) learnFromEvents(rootPid int, fmod fileMod, path string, event uint32) {
+func (w *FileWatch) learnFromEvents(rootPid int, fmod fileMod, path string, event uint32, modeChanged, ownerChanged bool, symlinkTarget string, diff string) {
 	// mLog.WithFields(log.Fields{"rootpid": rootPid, "path": path, "event": event}).Debug()
 	w.mux.Lock()
 	grp, ok := w.groups[rootPid]
@@ -428,8 +1876,12 @@ func (w *FileWatch) learnFromEvents(rootPid int, fmod fileMod, path string, even
 		w.mux.Unlock()
 		return
 	}
+	if grp.paused {
+		w.mux.Unlock()
+		return
+	}
 	mode := grp.mode
-	if mode == share.PolicyModeLearn && len(fmod.pInfo) > 0 {	// inotify has no process info
+	if mode == share.PolicyModeLearn && len(fmod.pInfo) > 0 { // inotify has no process info
 		for _, flt := range grp.profile.Filters {
 			if flt.CustomerAdd && filterPathMatch(path, flt) {
 				addLearnedRules(grp, flt, fmod.pInfo)
@@ -444,10 +1896,9 @@ func (w *FileWatch) learnFromEvents(rootPid int, fmod fileMod, path string, even
 	}
 	w.mux.Unlock()
 
-
 	// it depends on the init conditions by runtime engine
-	if isRunTimeAddedFile(filepath.Join("/root", path)) {
-		if event == fileEventAccessed || time.Since(grp.startAt) < time.Duration(time.Second * 60) {
+	if w.isRunTimeAddedFile(filepath.Join("/root", path)) {
+		if event == fileEventAccessed || time.Since(grp.startAt) < time.Duration(time.Second*60) {
 			return
 		}
 	}
@@ -460,7 +1911,40 @@ func (w *FileWatch) learnFromEvents(rootPid int, fmod fileMod, path string, even
 				path = path[index+5:]
 			}
 		}
-		w.sendMsg(fmod.finfo.ContainerId, path, event, fmod.pInfo, mode)
+
+		w.mux.Lock()
+		pInfo := filterProcInfoByEUID(grp, path, fmod.pInfo)
+		critical := pathCritical(grp.criticalMounts, path)
+		captureFDs := filterCaptureOpenFDs(grp, path)
+		newNsMsgs := w.detectNewMountNamespaces(grp, fmod.finfo.ContainerId, fmod.pInfo)
+		w.mux.Unlock()
+		for _, m := range newNsMsgs {
+			w.report(m)
+		}
+		if len(fmod.pInfo) > 0 && len(pInfo) == 0 {
+			// every process was excluded by the filter's EUIDs condition
+			return
+		}
+		if w.coalescePackages && isPackageManagerPath(path) {
+			w.recordPackageActivity(fmod.finfo.ContainerId)
+			return
+		}
+		if w.aggregationMode == AggregationByPath {
+			// collapse to a single message for the path, regardless of how
+			// many distinct processes touched it.
+			pInfo = nil
+		}
+		if key := matchedFilterKey(grp, path); key != "" {
+			w.mux.Lock()
+			counts, ok := w.filterEvents[rootPid]
+			if !ok {
+				counts = make(map[string]int64)
+				w.filterEvents[rootPid] = counts
+			}
+			counts[key]++
+			w.mux.Unlock()
+		}
+		w.sendMsg(rootPid, fmod.finfo.ContainerId, path, event, pInfo, mode, fmod.anomalous, fmod.finfo.Capabilities, critical, captureFDs, modeChanged, ownerChanged, symlinkTarget, diff)
 	}
 }
 
@@ -490,7 +1974,9 @@ func (w *FileWatch) UpdateAccessRules(name string, rootPid int, conf *share.CLUS
 	}
 	w.mux.Unlock()
 
-	w.fanotifier.UpdateAccessRule(rootPid, conf)
+	if w.fanotifier != nil {
+		w.fanotifier.UpdateAccessRule(rootPid, conf)
+	}
 }
 
 func (w *FileWatch) Close() {
@@ -499,6 +1985,13 @@ func (w *FileWatch) Close() {
 		return
 	}
 
+	close(w.done) // stop loop(), so it doesn't race the flushes below
+
+	// flush any not-yet-reported learned rules and pending batched messages,
+	// so a graceful shutdown doesn't silently drop them.
+	w.reportLearningRules()
+	w.flushBatch()
+
 	if w.fanotifier != nil {
 		w.fanotifier.Close()
 	}
@@ -514,27 +2007,62 @@ func (w *FileWatch) cbNotify(filePath string, mask uint32, params interface{}, p
 		return
 	}
 
+	if w.rawEventCallback != nil {
+		cid := ""
+		if finfo, ok := params.(*osutil.FileInfoExt); ok {
+			cid = finfo.ContainerId
+		}
+		// invoked before the w.mux-guarded aggregation below and without
+		// holding w.mux, so a slow or blocking callback can't stall the
+		// notifier goroutines that feed cbNotify.
+		w.rawEventCallback(cid, filePath, mask, pInfo)
+	}
+
 	w.mux.Lock()
 	defer w.mux.Unlock()
+
+	anomalous := w.observePathRate(filePath)
+
 	if fm, ok := w.fileEvents[filePath]; ok {
-		fm.mask |= mask
-		fm.delay = 0
+		target := fm
+		if w.aggregationMode == AggregationByEvent && fm.mask != mask {
+			target = nil
+			for _, e := range fm.extra {
+				if e.mask == mask {
+					target = e
+					break
+				}
+			}
+			if target == nil {
+				target = &fileMod{mask: mask, finfo: params.(*osutil.FileInfoExt)}
+				fm.extra = append(fm.extra, target)
+			}
+		}
+		target.mask |= mask
+		target.delay = 0
+		target.anomalous = target.anomalous || anomalous
 		if pInfo != nil {
 			var found bool
-			for _, p := range fm.pInfo {
+			for _, p := range target.pInfo {
 				if p.Pid == pInfo.Pid {
 					found = true
 					break
 				}
 			}
 			if !found {
-				fm.pInfo = append(fm.pInfo, pInfo)
+				target.pInfo = append(target.pInfo, pInfo)
 			}
 		}
 	} else {
-		fmod := &fileMod {
-			mask:  mask,
-			finfo: params.(*osutil.FileInfoExt),
+		if w.maxPendingEvents > 0 && len(w.fileEvents) >= w.maxPendingEvents {
+			w.droppedEvents++
+			log.WithFields(log.Fields{"path": filePath, "pending": len(w.fileEvents)}).Warn("FMON: pending event cap reached, dropping event")
+			return
+		}
+		fmod := &fileMod{
+			mask:      mask,
+			finfo:     params.(*osutil.FileInfoExt),
+			anomalous: anomalous,
 		}
 		if pInfo != nil {
 			fmod.pInfo = append(fmod.pInfo, pInfo)
@@ -543,22 +2071,158 @@ func (w *FileWatch) cbNotify(filePath string, mask uint32, params interface{}, p
 	}
 }
 
+// watchPriority ranks finfo for admission against FileMonitorConfig.MaxWatches:
+// lower values are admitted first when the node-wide watch budget is tight.
+// Filters with blocking (protect) behavior are the reason monitoring exists
+// in enforce mode, so they're admitted first; ordinary single-file filters
+// come next; broad recursive/wildcard filters, which tend to expand into the
+// most watches for the least specific coverage, are admitted last.
+func watchPriority(finfo *osutil.FileInfoExt) int {
+	if finfo.Protect {
+		return 0
+	}
+	if flt, ok := finfo.Filter.(*filterRegex); ok && flt.recursive {
+		return 2
+	}
+	return 1
+}
+
+// admitWatch enforces w.watchBudget, the node-wide cap on total watched
+// files/directories (see FileMonitorConfig.MaxWatches). It returns false,
+// without reserving a slot, once the budget is exhausted; callers must then
+// skip creating the watch. A zero budget disables the check.
+func (w *FileWatch) admitWatch() bool {
+	if w.watchBudget <= 0 {
+		return true
+	}
+	w.budgetMu.Lock()
+	defer w.budgetMu.Unlock()
+	if w.watchCount >= w.watchBudget {
+		return false
+	}
+	w.watchCount++
+	return true
+}
+
+// releaseWatch gives back a slot reserved by admitWatch. Accounting is
+// best-effort: a path removed from inside an already-watched directory
+// didn't reserve its own slot, so releasing it is a no-op once the budget
+// would go negative.
+func (w *FileWatch) releaseWatch() {
+	if w.watchBudget <= 0 {
+		return
+	}
+	w.budgetMu.Lock()
+	defer w.budgetMu.Unlock()
+	if w.watchCount > 0 {
+		w.watchCount--
+	}
+}
+
+// admitContainerWatch enforces FileMonitorConfig.MaxWatchesPerContainer for
+// cid, independent of the node-wide watchBudget. Returns false, without
+// reserving a slot, once cid's cap is reached; the caller must then skip
+// creating the watch. Only the transition into truncation logs a warning,
+// so a container stuck at the cap doesn't spam one per skipped file. A zero
+// cap, or an empty cid, disables the check.
+func (w *FileWatch) admitContainerWatch(cid string) bool {
+	if w.maxWatchesPerContainer <= 0 || cid == "" {
+		return true
+	}
+	w.containerWatchMu.Lock()
+	defer w.containerWatchMu.Unlock()
+	if w.containerWatchCount[cid] >= w.maxWatchesPerContainer {
+		if !w.containerTruncated[cid] {
+			w.containerTruncated[cid] = true
+			log.WithFields(log.Fields{"cid": cid, "limit": w.maxWatchesPerContainer}).Warn("FMON: container watch-count limit reached, monitoring truncated")
+		}
+		return false
+	}
+	w.containerWatchCount[cid]++
+	return true
+}
+
+// releaseContainerWatch gives back a slot reserved by admitContainerWatch.
+func (w *FileWatch) releaseContainerWatch(cid string) {
+	if w.maxWatchesPerContainer <= 0 || cid == "" {
+		return
+	}
+	w.containerWatchMu.Lock()
+	defer w.containerWatchMu.Unlock()
+	if w.containerWatchCount[cid] > 0 {
+		w.containerWatchCount[cid]--
+	}
+}
+
+// forgetContainerWatch drops cid's per-container watch bookkeeping entirely,
+// called from ContainerCleanup once a container has left so a future
+// container reusing the same id, however unlikely, doesn't inherit a stale
+// truncated flag or count.
+func (w *FileWatch) forgetContainerWatch(cid string) {
+	if cid == "" {
+		return
+	}
+	w.containerWatchMu.Lock()
+	delete(w.containerWatchCount, cid)
+	delete(w.containerTruncated, cid)
+	w.containerWatchMu.Unlock()
+}
+
 func (w *FileWatch) addFile(bIncInotify bool, finfo *osutil.FileInfoExt) {
 	if !w.bEnable {
 		return
 	}
 
-	w.fanotifier.AddMonitorFile(finfo.Path, finfo.Filter, finfo.Protect, finfo.UserAdded, w.cbNotify, finfo)
+	if !w.admitWatch() {
+		log.WithFields(log.Fields{"path": finfo.Path}).Warn("FMON: watch budget exhausted, skipping watch")
+		return
+	}
+	if !w.admitContainerWatch(finfo.ContainerId) {
+		w.releaseWatch()
+		return
+	}
+
+	if w.fanotifier != nil {
+		w.fanotifier.AddMonitorFile(finfo.Path, finfo.Filter, finfo.Protect, finfo.UserAdded, w.cbNotify, finfo)
+	}
 	//if _, path := global.SYS.ParseContainerFilePath(finfo.Path); packageFile.Contains(path) {
 	flt := finfo.Filter.(*filterRegex)
-	if bIncInotify && !strings.HasSuffix(flt.path, "/.*") { // this wildcard has established its directory for all
+	if w.inotifier != nil && bIncInotify && !strings.HasSuffix(flt.path, "/.*") { // this wildcard has established its directory for all
 		w.inotifier.AddMonitorFile(finfo.Path, w.cbNotify, finfo)
 	}
 }
 
 func (w *FileWatch) removeFile(fullpath string) {
-	w.fanotifier.RemoveMonitorFile(fullpath) // should not
-	w.inotifier.RemoveMonitorFile(fullpath)
+	if w.fanotifier != nil {
+		w.fanotifier.RemoveMonitorFile(fullpath) // should not
+	}
+	if w.inotifier != nil {
+		w.inotifier.RemoveMonitorFile(fullpath)
+	}
+	w.releaseWatch()
+	if w.maxWatchesPerContainer > 0 {
+		if pid, _ := global.SYS.ParseContainerFilePath(fullpath); osutil.IsPidValid(pid) {
+			w.mux.Lock()
+			cid := ""
+			if grp, ok := w.groups[pid]; ok {
+				cid = grp.cid
+			}
+			w.mux.Unlock()
+			w.releaseContainerWatch(cid)
+		}
+	}
+
+	if w.captureDiff {
+		w.diffMu.Lock()
+		delete(w.diffCache, fullpath)
+		w.diffMu.Unlock()
+	}
+
+	if w.hashCacheTTL > 0 {
+		w.hashCacheMu.Lock()
+		delete(w.hashCache, fullpath)
+		w.hashCacheMu.Unlock()
+	}
 }
 
 func (w *FileWatch) addDir(bIncInotify bool, finfo *osutil.FileInfoExt, files map[string]*osutil.FileInfoExt) {
@@ -566,20 +2230,39 @@ func (w *FileWatch) addDir(bIncInotify bool, finfo *osutil.FileInfoExt, files ma
 		return
 	}
 
+	if !w.admitWatch() {
+		log.WithFields(log.Fields{"path": finfo.Path}).Warn("FMON: watch budget exhausted, skipping watch")
+		return
+	}
+	if !w.admitContainerWatch(finfo.ContainerId) {
+		w.releaseWatch()
+		return
+	}
+
 	ff := make(map[string]interface{})
 	for fpath, fi := range files {
 		ff[fpath] = fi
 	}
 
-	w.fanotifier.AddMonitorDirFile(finfo.Path, finfo.Filter, finfo.Protect, finfo.UserAdded, ff, w.cbNotify, finfo)
-	if bIncInotify {
+	if w.fanotifier != nil {
+		w.fanotifier.AddMonitorDirFile(finfo.Path, finfo.Filter, finfo.Protect, finfo.UserAdded, ff, w.cbNotify, finfo)
+	}
+	if w.inotifier != nil && bIncInotify {
 		w.inotifier.AddMonitorDirFile(finfo.Path, nil, w.cbNotify, finfo)
 	}
 }
 
+// getDirAndFileList resolves path/regx to concrete dirs and files and merges
+// the dirs into dirList. mu, when non-nil, guards that merge for callers that
+// invoke this concurrently across filters (see getCoreFile); callers that
+// only ever call it from one goroutine, like AddFilter, pass nil.
 func (w *FileWatch) getDirAndFileList(pid int, path, regx, cid string, filter *filterRegex, recur, protect, userAdded bool,
-	dirList map[string]*osutil.FileInfoExt) []*osutil.FileInfoExt {
-	dirs, singles := w.getDirFileList(pid, path, regx, cid, filter, recur, protect, userAdded)
+	dirList map[string]*osutil.FileInfoExt, mu *sync.Mutex, rootWalkTimeout time.Duration) []*osutil.FileInfoExt {
+	dirs, singles := w.getDirFileList(pid, path, regx, cid, filter, recur, protect, userAdded, rootWalkTimeout)
+	if mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
 	for _, di := range dirs {
 		if diExist, ok := dirList[di.Path]; ok {
 			diExist.Children = append(diExist.Children, di.Children...)
@@ -590,61 +2273,87 @@ func (w *FileWatch) getDirAndFileList(pid int, path, regx, cid string, filter *f
 	return singles
 }
 
-func (w *FileWatch) getCoreFile(cid string, pid int, profile *share.CLUSFileMonitorProfile) (map[string]*osutil.FileInfoExt, []*osutil.FileInfoExt) {
+// collectFilterFiles resolves filter to its concrete watched dirs/files and
+// merges them into dirList/singleFiles, both shared across the concurrent
+// callers spawned by getCoreFile and guarded by mu.
+func (w *FileWatch) collectFilterFiles(pid int, cid string, filter share.CLUSFileMonitorFilter,
+	dirList map[string]*osutil.FileInfoExt, mu *sync.Mutex, singleFiles *[]*osutil.FileInfoExt, rootWalkTimeout time.Duration) {
+	flt := &filterRegex{path: filterIndexKey(filter), recursive: filter.Recursive, base: strings.TrimSuffix(filter.Path, "/"), maxDepth: filter.MaxDepth}
+	flt.regex, _ = regexp.Compile(fmt.Sprintf("^%s$", flt.path))
+	bBlockAccess := filter.Behavior == share.FileAccessBehaviorBlock
+	bUserAdded := filter.CustomerAdd
+
+	var singles []*osutil.FileInfoExt
+	if strings.Contains(filter.Path, "*") {
+		for _, sub := range w.getSubDirList(pid, filter.Path, cid) {
+			singles = append(singles, w.getDirAndFileList(pid, sub, filter.Regex, cid, flt, filter.Recursive, bBlockAccess, bUserAdded, dirList, mu, rootWalkTimeout)...)
+		}
+	} else {
+		singles = w.getDirAndFileList(pid, filter.Path, filter.Regex, cid, flt, filter.Recursive, bBlockAccess, bUserAdded, dirList, mu, rootWalkTimeout)
+	}
+
+	mu.Lock()
+	*singleFiles = append(*singleFiles, singles...)
+	mu.Unlock()
+}
+
+// getCoreFile resolves every filter in profile (both cluster-defined and
+// CRD-managed) to its concrete dirs/files. Filters are resolved concurrently,
+// bounded by defaultWalkerConcurrency workers, since each filter's rootfs
+// walk is I/O-bound and independent of the others; results are merged into a
+// shared dirList/singleFiles under a mutex.
+func (w *FileWatch) getCoreFile(cid string, pid int, profile *share.CLUSFileMonitorProfile, rootWalkTimeout time.Duration) (map[string]*osutil.FileInfoExt, []*osutil.FileInfoExt) {
 	dirList := make(map[string]*osutil.FileInfoExt)
 	singleFiles := make([]*osutil.FileInfoExt, 0)
 
-	// get files and dirs from all filters
-	for _, filter := range profile.Filters {
-		flt := &filterRegex{path: filterIndexKey(filter), recursive: filter.Recursive}
-		flt.regex, _ = regexp.Compile(fmt.Sprintf("^%s$", flt.path))
-		bBlockAccess := filter.Behavior == share.FileAccessBehaviorBlock
-		bUserAdded := filter.CustomerAdd
-		if strings.Contains(filter.Path, "*") {
-			subDirs := w.getSubDirList(pid, filter.Path, cid)
-			for _, sub := range subDirs {
-				singles := w.getDirAndFileList(pid, sub, filter.Regex, cid, flt, filter.Recursive, bBlockAccess, bUserAdded, dirList)
-				singleFiles = append(singleFiles, singles...)
-			}
-		} else {
-			singles := w.getDirAndFileList(pid, filter.Path, filter.Regex, cid, flt, filter.Recursive, bBlockAccess, bUserAdded, dirList)
-			singleFiles = append(singleFiles, singles...)
-		}
-	}
-
-	// get files and dirs from all filters
-	for _, filter := range profile.FiltersCRD {
-		flt := &filterRegex{path: filterIndexKey(filter), recursive: filter.Recursive}
-		flt.regex, _ = regexp.Compile(fmt.Sprintf("^%s$", flt.path))
-		bBlockAccess := filter.Behavior == share.FileAccessBehaviorBlock
-		bUserAdded := filter.CustomerAdd
-		if strings.Contains(filter.Path, "*") {
-			subDirs := w.getSubDirList(pid, filter.Path, cid)
-			for _, sub := range subDirs {
-				singles := w.getDirAndFileList(pid, sub, filter.Regex, cid, flt, filter.Recursive, bBlockAccess, bUserAdded, dirList)
-				singleFiles = append(singleFiles, singles...)
-			}
-		} else {
-			singles := w.getDirAndFileList(pid, filter.Path, filter.Regex, cid, flt, filter.Recursive, bBlockAccess, bUserAdded, dirList)
-			singleFiles = append(singleFiles, singles...)
-		}
+	filters := make([]share.CLUSFileMonitorFilter, 0, len(profile.Filters)+len(profile.FiltersCRD))
+	filters = append(filters, profile.Filters...)
+	filters = append(filters, profile.FiltersCRD...)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, defaultWalkerConcurrency)
+
+	for _, filter := range filters {
+		filter := filter
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			w.collectFilterFiles(pid, cid, filter, dirList, &mu, &singleFiles, rootWalkTimeout)
+		}()
 	}
+	wg.Wait()
+
 	return dirList, singleFiles
 }
 
-//
-func isRunTimeAddedFile(path string) bool {
-	return strings.HasSuffix(path, "/root/etc/hosts") ||
-		strings.HasSuffix(path, "/root/etc/hostname") ||
-		strings.HasSuffix(path, "/root/etc/resolv.conf")
+// isRunTimeAddedFile reports whether path (rooted at "/root", i.e. a
+// /proc/<pid>/root/... view) is one of w.runtimeManagedPaths, see
+// FileMonitorConfig.RuntimeManagedPaths.
+func (w *FileWatch) isRunTimeAddedFile(path string) bool {
+	for _, p := range w.runtimeManagedPaths {
+		if strings.HasSuffix(path, filepath.Join("/root", p)) {
+			return true
+		}
+	}
+	return false
 }
 
 func (w *FileWatch) addCoreFile(bIncINotify bool, cid string, dirList map[string]*osutil.FileInfoExt, singleFiles []*osutil.FileInfoExt) {
+	// admit by priority, so under a tight watch budget (FileMonitorConfig.
+	// MaxWatches) the highest-priority filters claim their slots before
+	// broad recursive ones do, see watchPriority.
+	sort.SliceStable(singleFiles, func(i, j int) bool {
+		return watchPriority(singleFiles[i]) < watchPriority(singleFiles[j])
+	})
+
 	// add files
 	for _, finfo := range singleFiles {
 		// need to move the cross link files to dirs
 		di, ok := dirList[filepath.Dir(finfo.Path)]
-		if ok && !isRunTimeAddedFile(finfo.Path) {
+		if ok && !w.isRunTimeAddedFile(finfo.Path) {
 			finfo.Filter = di.Filter
 			di.Children = append(di.Children, finfo)
 		} else {
@@ -653,22 +2362,146 @@ func (w *FileWatch) addCoreFile(bIncINotify bool, cid string, dirList map[string
 		}
 	}
 
-	// add directories
-	for _, dir := range dirList {
-		if dir == nil {
-			continue
-		}
-		files := make(map[string]*osutil.FileInfoExt)
-		for _, file := range dir.Children {
-			if file == nil {
-				continue
-			}
-			file.ContainerId = cid
-			files[filepath.Base(file.Path)] = file
-		}
-		dir.ContainerId = cid
-		w.addDir(bIncINotify, dir, files)
+	// add directories
+	dirs := make([]*osutil.FileInfoExt, 0, len(dirList))
+	for _, dir := range dirList {
+		if dir != nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return watchPriority(dirs[i]) < watchPriority(dirs[j])
+	})
+
+	for _, dir := range dirs {
+		files := make(map[string]*osutil.FileInfoExt)
+		for _, file := range dir.Children {
+			if file == nil {
+				continue
+			}
+			file.ContainerId = cid
+			files[filepath.Base(file.Path)] = file
+		}
+		dir.ContainerId = cid
+		w.addDir(bIncINotify, dir, files)
+	}
+}
+
+// handleQueueOverflow is the inotifier's OverflowCallback: it fires when the
+// kernel inotify queue overflows (IN_Q_OVERFLOW) and events were dropped,
+// which would otherwise leave FileInfoExt hashes stale and produce false
+// "no change" results the next time a dropped-event file is actually
+// checked. Since the overflow itself doesn't identify which watch it hit,
+// every currently watched container is re-baselined via the same
+// getCoreFile/addCoreFile walk StartWatch uses, subject to
+// resyncOverflowInterval per container.
+func (w *FileWatch) handleQueueOverflow() {
+	log.Warn("FMON: inotify queue overflow, resyncing watched containers")
+
+	type resyncTarget struct {
+		cid         string
+		rootPid     int
+		profile     *share.CLUSFileMonitorProfile
+		walkTimeout time.Duration
+		bNeuvector  bool
+	}
+
+	now := time.Now()
+	w.mux.Lock()
+	targets := make([]resyncTarget, 0, len(w.groups))
+	for rootPid, grp := range w.groups {
+		if last, ok := w.lastResync[rootPid]; ok && now.Sub(last) < resyncOverflowInterval {
+			continue
+		}
+		w.lastResync[rootPid] = now
+		targets = append(targets, resyncTarget{
+			cid:         grp.cid,
+			rootPid:     rootPid,
+			profile:     grp.profile,
+			walkTimeout: grp.walkTimeout,
+			bNeuvector:  grp.bNeuvector,
+		})
+	}
+	w.mux.Unlock()
+
+	for _, t := range targets {
+		dirs, files := w.getCoreFile(t.cid, t.rootPid, t.profile, t.walkTimeout)
+		w.addCoreFile(!t.bNeuvector, t.cid, dirs, files)
+	}
+}
+
+// modeAccessPerm derives the fanotifier access/perm flags for mode, mirroring
+// the policy-mode-to-enforcement mapping StartWatch applies at watch setup,
+// so OverrideMode can recompute the same flags for a runtime mode change.
+func (w *FileWatch) modeAccessPerm(mode string, rootPid int, bNeuvectorSvc, capBlock bool) (access, perm bool) {
+	if mode == share.PolicyModeEnforce && !w.aufs && capBlock { // system containers will be limited at monitor mode
+		perm = true
+	} else if rootPid == 1 || bNeuvectorSvc {
+		// skip learn host and our container. only notify on modified
+		access = false
+	} else if mode == share.PolicyModeLearn { // only for discover mode
+		access = true
+	}
+	return access, perm
+}
+
+// OverrideMode downgrades or restores a running group's enforcement mode at
+// runtime, without re-walking its filesystem, so a noisy group can be forced
+// into monitor-only to triage before it's allowed to block again. mode must
+// be one of the known share.PolicyMode* constants.
+func (w *FileWatch) OverrideMode(rootPid int, mode string) error {
+	switch mode {
+	case share.PolicyModeLearn, share.PolicyModeEvaluate, share.PolicyModeEnforce:
+	default:
+		return fmt.Errorf("FMON: unknown mode %s", mode)
+	}
+
+	w.mux.Lock()
+	grp, ok := w.groups[rootPid]
+	if !ok {
+		w.mux.Unlock()
+		return fmt.Errorf("FMON: group not found, pid=%d", rootPid)
+	}
+	grp.mode = mode
+	bNeuvectorSvc := grp.bNeuvector
+	capBlock := grp.capBlock
+	dryRun := grp.dryRun && mode == share.PolicyModeEnforce
+	w.mux.Unlock()
+
+	if w.fanotifier != nil {
+		access, perm := w.modeAccessPerm(mode, rootPid, bNeuvectorSvc, capBlock)
+		w.fanotifier.SetMode(rootPid, access, perm, capBlock, bNeuvectorSvc, dryRun)
+	}
+	return nil
+}
+
+// PauseContainer suspends event reporting for rootPid's container, e.g.
+// during a planned maintenance window that would otherwise generate a flood
+// of noise. Fanotify/inotify watches and the learned baseline are left
+// untouched; events are simply discarded in learnFromEvents until
+// ResumeContainer is called.
+func (w *FileWatch) PauseContainer(rootPid int) error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	grp, ok := w.groups[rootPid]
+	if !ok {
+		return fmt.Errorf("FMON: group not found, pid=%d", rootPid)
+	}
+	grp.paused = true
+	return nil
+}
+
+// ResumeContainer reverses PauseContainer, so events observed after the call
+// are reported normally again.
+func (w *FileWatch) ResumeContainer(rootPid int) error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	grp, ok := w.groups[rootPid]
+	if !ok {
+		return fmt.Errorf("FMON: group not found, pid=%d", rootPid)
 	}
+	grp.paused = false
+	return nil
 }
 
 func (w *FileWatch) StartWatch(id string, rootPid int, conf *FsmonConfig, capBlock, bNeuvectorSvc bool) {
@@ -688,26 +2521,23 @@ func (w *FileWatch) StartWatch(id string, rootPid int, conf *FsmonConfig, capBlo
 	if conf.Profile.Mode == "" {
 		conf.Profile.Mode = share.PolicyModeLearn
 	}
-	var access, perm bool
-	if conf.Profile.Mode == share.PolicyModeEnforce && !w.aufs && capBlock { // system containers will be limited at monitor mode
-		perm = true
-	} else {
-		if rootPid == 1 || bNeuvectorSvc {
-			// skip learn host and our container. only notify on modified
-			access = false
-		} else {
-			if conf.Profile.Mode == share.PolicyModeLearn { // only for discover mode
-				access = true
-			}
-		}
-	}
-	dirs, files := w.getCoreFile(id, rootPid, conf.Profile)
+	access, perm := w.modeAccessPerm(conf.Profile.Mode, rootPid, bNeuvectorSvc, capBlock)
+	dirs, files := w.getCoreFile(id, rootPid, conf.Profile, conf.WalkTimeout)
 
-	w.fanotifier.SetMode(rootPid, access, perm, capBlock, bNeuvectorSvc)
+	if w.fanotifier != nil {
+		dryRun := conf.DryRunEnforce && conf.Profile.Mode == share.PolicyModeEnforce
+		w.fanotifier.SetMode(rootPid, access, perm, capBlock, bNeuvectorSvc, dryRun)
+	}
 
 	w.addCoreFile(!bNeuvectorSvc, id, dirs, files)
 
-	w.fanotifier.StartMonitor(rootPid)
+	if w.fanotifier != nil {
+		w.fanotifier.StartMonitor(rootPid)
+	}
+
+	if w.baselineReady != nil {
+		w.baselineReady(id, rootPid, len(dirs)+len(files))
+	}
 
 	w.mux.Lock()
 	grp, ok := w.groups[rootPid]
@@ -716,14 +2546,36 @@ func (w *FileWatch) StartWatch(id string, rootPid int, conf *FsmonConfig, capBlo
 			bNeuvector: bNeuvectorSvc,
 			learnRules: make(map[string]utils.Set),
 			applyRules: make(map[string]utils.Set),
-			startAt: time.Now(),
+			startAt:    time.Now(),
+			seenMntNs:  make(map[uint64]bool),
 		}
+		grp.rootMntNs = global.SYS.GetMntNamespaceId(rootPid)
 		w.groups[rootPid] = grp
 	}
+	grp.cid = id
 	grp.profile = conf.Profile
 	grp.mode = conf.Profile.Mode
+	grp.excludes = compileExcludePaths(conf.ExcludePaths)
+	grp.criticalMounts = conf.SensitiveMounts
+	grp.walkTimeout = conf.WalkTimeout
+	grp.capBlock = capBlock
+	grp.dryRun = conf.DryRunEnforce
 	w.mux.Unlock()
 
+	// On aufs/overlay, an event for a file in the container's writable layer
+	// can resolve to a host path under its upperdir instead of the usual
+	// /proc/<pid>/root/... form ParseContainerFilePath expects. Record the
+	// mapping so HandleWatchedFiles can still attribute it, see
+	// resolveByUpperDir. Best-effort: a lookup failure just means that
+	// fallback won't be available for this container.
+	if w.aufs {
+		if upperDir, _, err := global.SYS.ReadMountedUppperLayerPath(rootPid, id); err == nil && upperDir != "" {
+			w.mux.Lock()
+			w.upperDirs[upperDir] = rootPid
+			w.mux.Unlock()
+		}
+	}
+
 	//// no access rules for neuvector and host
 	if bNeuvectorSvc || rootPid == 1 {
 		return
@@ -734,40 +2586,228 @@ func (w *FileWatch) StartWatch(id string, rootPid int, conf *FsmonConfig, capBlo
 	}
 }
 
+// StartWatchBatch runs StartWatch for every entry in reqs concurrently,
+// rather than one at a time, so that at node startup dozens of containers'
+// rootfs walks overlap instead of queueing behind each other. This doesn't
+// raise the number of walks actually running at once -- that's still capped
+// by walkerLimiter inside getCoreFile -- it only lets containers whose
+// walks finish quickly stop waiting on a slow one ahead of them.
+//
+// The returned slice is index-aligned with reqs: an entry is non-nil only
+// for a request StartWatch itself would have rejected (e.g. !IsPidValid), so
+// the caller can tell which containers never got watched.
+func (w *FileWatch) StartWatchBatch(reqs []StartWatchReq) []error {
+	errs := make([]error, len(reqs))
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		if !osutil.IsPidValid(req.RootPid) {
+			errs[i] = fmt.Errorf("FMON: invalid Pid, id=%s pid=%d", req.ID, req.RootPid)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, req StartWatchReq) {
+			defer wg.Done()
+			w.StartWatch(req.ID, req.RootPid, req.Conf, req.CapBlock, req.BNeuvectorSvc)
+		}(i, req)
+	}
+	wg.Wait()
+	return errs
+}
+
+// removeCRDFilter drops filter from filters by its index key, returning the
+// remaining slice. Used by RemoveFilter to keep groupInfo.profile.FiltersCRD
+// in sync with what's actually being watched.
+func removeCRDFilter(filters []share.CLUSFileMonitorFilter, filter share.CLUSFileMonitorFilter) []share.CLUSFileMonitorFilter {
+	key := filterIndexKey(filter)
+	out := filters[:0]
+	for _, f := range filters {
+		if filterIndexKey(f) != key {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// AddFilter incrementally adds a single CRD-managed filter to the container
+// rooted at rootPid, without re-walking the whole profile the way StartWatch
+// does: only the paths this filter resolves to are computed (reusing
+// getDirAndFileList) and handed to addFile/addDir. The container must
+// already be watched via StartWatch.
+func (w *FileWatch) AddFilter(rootPid int, filter share.CLUSFileMonitorFilter) error {
+	if !w.bEnable {
+		return nil
+	}
+
+	w.mux.Lock()
+	grp, ok := w.groups[rootPid]
+	if !ok {
+		w.mux.Unlock()
+		return fmt.Errorf("fsmon: container at pid %d is not being watched", rootPid)
+	}
+	cid, bNeuvector, walkTimeout := grp.cid, grp.bNeuvector, grp.walkTimeout
+	w.mux.Unlock()
+
+	dirList := make(map[string]*osutil.FileInfoExt)
+	singleFiles := make([]*osutil.FileInfoExt, 0)
+	var mu sync.Mutex
+	w.collectFilterFiles(rootPid, cid, filter, dirList, &mu, &singleFiles, walkTimeout)
+
+	w.addCoreFile(!bNeuvector, cid, dirList, singleFiles)
+
+	w.mux.Lock()
+	grp.profile.FiltersCRD = append(grp.profile.FiltersCRD, filter)
+	w.mux.Unlock()
+	return nil
+}
+
+// RemoveFilter undoes a prior AddFilter (or a matching profile filter),
+// unwatching only the concrete paths currently attributed to it -- found via
+// FaNotify.FilterCoverage -- rather than reapplying the whole profile.
+func (w *FileWatch) RemoveFilter(rootPid int, filter share.CLUSFileMonitorFilter) error {
+	if !w.bEnable {
+		return nil
+	}
+
+	w.mux.Lock()
+	grp, ok := w.groups[rootPid]
+	if !ok {
+		w.mux.Unlock()
+		return fmt.Errorf("fsmon: container at pid %d is not being watched", rootPid)
+	}
+	w.mux.Unlock()
+
+	coverage := w.FilterCoverage(rootPid)
+	paths := coverage[filterIndexKey(filter)]
+	if len(paths) > 0 {
+		ppath := fmt.Sprintf(procRootMountPoint, rootPid)
+		for _, p := range paths {
+			w.removeFile(ppath + p)
+		}
+	}
+
+	w.mux.Lock()
+	grp.profile.FiltersCRD = removeCRDFilter(grp.profile.FiltersCRD, filter)
+	w.mux.Unlock()
+	return nil
+}
+
+// resolveByUpperDir is HandleWatchedFiles' fallback when
+// ParseContainerFilePath can't map fullPath to a (pid, relpath) -- which
+// happens on aufs/overlay for a path that resolved to the container's
+// upperdir instead of its /proc/<pid>/root/... mount view. It returns the
+// owning rootPid and the container-relative path if fullPath falls under a
+// known upperdir recorded by StartWatch.
+func (w *FileWatch) resolveByUpperDir(fullPath string) (int, string, bool) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	for upperDir, rootPid := range w.upperDirs {
+		if rel := strings.TrimPrefix(fullPath, upperDir); rel != fullPath && (rel == "" || rel[0] == '/') {
+			return rootPid, rel, true
+		}
+	}
+	return 0, "", false
+}
+
+// isPlainCreateMask reports whether mask is a lone create/moved-in with no
+// accompanying delete/moved-from bits that would already supersede it, see
+// FileMonitorConfig.CreateDebounce.
+func isPlainCreateMask(mask uint32) bool {
+	const supersedingMask = syscall.IN_DELETE | syscall.IN_DELETE_SELF | syscall.IN_MOVED_FROM | syscall.IN_MOVE_SELF
+	return (mask&syscall.IN_CREATE) != 0 && (mask&supersedingMask) == 0
+}
+
 func (w *FileWatch) HandleWatchedFiles() {
 	events := make(map[string]fileMod)
 
-	// clone events
+	// clone events, holding back a still-fresh lone create for another tick
+	// if CreateDebounce is set, instead of draining it immediately -- see
+	// isPlainCreateMask and cbNotify's target.delay reset.
 	w.mux.Lock()
+	held := make(map[string]*fileMod)
 	for filePath, fmod := range w.fileEvents {
+		if w.createDebounceTicks > 0 && len(fmod.extra) == 0 && isPlainCreateMask(fmod.mask) {
+			fmod.delay++
+			if fmod.delay < w.createDebounceTicks {
+				held[filePath] = fmod
+				continue
+			}
+		}
 		events[filePath] = *fmod
 	}
-	w.fileEvents = make(map[string]*fileMod) // reset
+	w.fileEvents = held // reset, keeping only what's still being debounced
 	w.mux.Unlock()
 
+	atomic.AddInt64(&w.totalEventsProcessed, int64(len(events)))
+
 	for fullPath, fmod := range events {
 		pid, path := global.SYS.ParseContainerFilePath(fullPath)
+		if !osutil.IsPidValid(pid) && w.aufs {
+			// the standard /proc/<pid>/root/... parse failed; on aufs/overlay
+			// this can be a path under a container's upperdir instead, see
+			// resolveByUpperDir.
+			if upid, upath, ok := w.resolveByUpperDir(fullPath); ok {
+				pid, path = upid, upath
+			}
+		}
 		// mLog.WithFields(log.Fields{"pid": pid, "path": path}).Debug()
 		//to avoid false alarm of /etc/hosts and /etc/resolv.conf, check whether the container is still exist
 		//these two files has attribute changed when the container leave
 		//this maybe miss some events file changed right before container leave. But for these kind of event,
 		//it is not useful if the container already leave
 		if osutil.IsPidValid(pid) { // for alive process
-			var event uint32
-			info, _ := os.Lstat(fullPath)
-			if fmod.finfo.FileMode.IsDir() || (info != nil && info.IsDir()) {
-				event = w.handleDirEvents(fmod, info, fullPath, path, pid)
-			} else {
-				event = w.handleFileEvents(fmod, info, fullPath, pid)
+			w.mux.Lock()
+			excluded := false
+			paused := false
+			if grp, ok := w.groups[pid]; ok {
+				excluded = pathExcluded(grp.excludes, path)
+				paused = grp.paused
+			}
+			w.mux.Unlock()
+			if excluded || paused {
+				continue
 			}
 
-			if event != 0 {
-				w.learnFromEvents(pid, fmod, path, event)
+			// under AggregationByEvent, fmod.extra holds additional distinct
+			// raw-mask event groups recorded for this same path; each is
+			// processed and reported independently, see cbNotify.
+			mods := make([]fileMod, 0, 1+len(fmod.extra))
+			mods = append(mods, fmod)
+			for _, e := range fmod.extra {
+				if e != nil {
+					mods = append(mods, *e)
+				}
+			}
+
+			for _, mod := range mods {
+				var event uint32
+				var modeChanged, ownerChanged bool
+				var symlinkTarget, diff string
+				info, _ := os.Lstat(fullPath)
+				if mod.finfo.FileMode.IsDir() || (info != nil && info.IsDir()) {
+					event = w.handleDirEvents(mod, info, fullPath, path, pid)
+				} else {
+					event, modeChanged, ownerChanged, symlinkTarget, diff = w.handleFileEvents(mod, info, fullPath, pid)
+				}
+
+				if event != 0 {
+					w.learnFromEvents(pid, mod, path, event, modeChanged, ownerChanged, symlinkTarget, diff)
+				}
 			}
 		}
 	}
 }
 
+// isOverlayWhiteout reports whether info describes an overlayfs whiteout marker:
+// a character device with major/minor 0/0 that overlayfs creates in the upper
+// layer to represent the deletion of a same-named lower-layer file.
+func isOverlayWhiteout(info os.FileInfo) bool {
+	if info == nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && st.Rdev == 0
+}
+
 // Decide the directory event priority here
 func (w *FileWatch) handleDirEvents(fmod fileMod, info os.FileInfo, fullPath, path string, pid int) uint32 {
 	var event uint32
@@ -792,7 +2832,20 @@ func (w *FileWatch) handleDirEvents(fmod fileMod, info os.FileInfo, fullPath, pa
 						log.WithFields(log.Fields{"id": fmod.finfo.ContainerId, "path": path}).Info("not recursive monitoring")
 						return event
 					}
+					if flt.maxDepth > 0 {
+						if depth := filterDepth(flt.base, path); depth > flt.maxDepth {
+							log.WithFields(log.Fields{"id": fmod.finfo.ContainerId, "path": path, "maxDepth": flt.maxDepth}).Debug("FMON: recursive filter depth limit reached, not watching new subdir")
+							return event
+						}
+					}
 				} else {
+					if isOverlayWhiteout(info) {
+						// overlayfs records the deletion of a lower-layer file as the
+						// creation of a whiteout in the upper layer, so report it as
+						// the removal it actually represents rather than a create.
+						w.removeFile(fullPath)
+						return fileEventRemoved
+					}
 					if info.Mode()&os.ModeSymlink != 0 {
 						// a new symbolic link
 						event = fileEventSymCreate
@@ -860,6 +2913,10 @@ func (w *FileWatch) handleDirEvents(fmod fileMod, info os.FileInfo, fullPath, pa
 				} else {
 					event = fileEventMovedFrom
 				}
+			} else if w.detectFileChurn && isFileChurn(fmod.mask) {
+				if !w.suppressFileChurn {
+					event = fileEventTransient
+				}
 			} else {
 				event = fileEventRemoved
 			}
@@ -869,9 +2926,104 @@ func (w *FileWatch) handleDirEvents(fmod fileMod, info os.FileInfo, fullPath, pa
 	return event
 }
 
+// isTextContent reports whether data looks like text rather than binary,
+// using the common heuristic of checking for a NUL byte -- text files don't
+// legitimately contain one.
+func isTextContent(data []byte) bool {
+	for _, b := range data {
+		if b == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// captureDiffSnippet computes a unified diff of fullPath's content against
+// the last content captureDiffSnippet cached for it, updating the cache with the
+// new content for next time. It returns "" if the file is missing, over
+// w.diffMaxSize, binary, or has no prior cached baseline to diff against.
+func (w *FileWatch) captureDiffSnippet(fullPath string) string {
+	fi, err := os.Stat(fullPath)
+	if err != nil || fi.Size() > w.diffMaxSize {
+		return ""
+	}
+	newContent, err := os.ReadFile(fullPath)
+	if err != nil || !isTextContent(newContent) {
+		return ""
+	}
+
+	w.diffMu.Lock()
+	oldContent, hadBaseline := w.diffCache[fullPath]
+	w.diffCache[fullPath] = append([]byte(nil), newContent...)
+	w.diffMu.Unlock()
+
+	if !hadBaseline {
+		return ""
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(oldContent)),
+		B:        difflib.SplitLines(string(newContent)),
+		FromFile: fullPath,
+		ToFile:   fullPath,
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return diff
+}
+
+// cachedFileHash returns osutil.GetFileHash(fullPath), consulting (and
+// refreshing) w.hashCache first so repeated events on the same path within
+// FileMonitorConfig.HashCacheTTL -- e.g. rapid modify/access churn on
+// /etc/hosts -- don't each re-read the file. A cached entry is only reused
+// while info's size and mtime still match what was hashed; anything else
+// falls through to a real read. Disabled entirely when HashCacheTTL is zero.
+func (w *FileWatch) cachedFileHash(fullPath string, info os.FileInfo) ([8]byte, error) {
+	if w.hashCacheTTL <= 0 {
+		return osutil.GetFileHash(fullPath)
+	}
+
+	size, modTime := info.Size(), info.ModTime()
+	now := time.Now()
+
+	w.hashCacheMu.Lock()
+	if e, ok := w.hashCache[fullPath]; ok && e.size == size && e.modTime.Equal(modTime) && now.Sub(e.cachedAt) < w.hashCacheTTL {
+		w.hashCacheMu.Unlock()
+		return e.hash, nil
+	}
+	w.hashCacheMu.Unlock()
+
+	hash, err := osutil.GetFileHash(fullPath)
+	if err != nil {
+		return hash, err
+	}
+
+	w.hashCacheMu.Lock()
+	if w.hashCacheSize > 0 && len(w.hashCache) >= w.hashCacheSize {
+		// Bounded, unordered eviction: this cache only exists to save I/O on
+		// hot repeated events, not to guarantee any particular hit rate, so a
+		// full LRU isn't worth the bookkeeping -- drop an arbitrary entry.
+		for k := range w.hashCache {
+			delete(w.hashCache, k)
+			break
+		}
+	}
+	w.hashCache[fullPath] = &hashCacheEntry{hash: hash, size: size, modTime: modTime, cachedAt: now}
+	w.hashCacheMu.Unlock()
+
+	return hash, nil
+}
+
 // Decide the file event priority here
-func (w *FileWatch) handleFileEvents(fmod fileMod, info os.FileInfo, fullPath string, pid int) uint32 {
-	var event uint32
+// handleFileEvents returns the detected event along with whether the change,
+// if any, was to the file's permission bits (modeChanged) and/or owning
+// uid/gid (ownerChanged). Both can be true at once, e.g. a single chmod+chown;
+// the returned event still picks one of fileEventChmod/fileEventChown for its
+// headline message (chown taking priority, since a re-owned file is usually
+// the more consequential change), and the caller surfaces both booleans on
+// MonitorMessage so neither is lost.
+func (w *FileWatch) handleFileEvents(fmod fileMod, info os.FileInfo, fullPath string, pid int) (event uint32, modeChanged bool, ownerChanged bool, symlinkTarget string, diff string) {
 	if info != nil {
 		log.WithFields(log.Fields{"fullPath": fullPath, "fmod": fmod, "finfo": fmod.finfo}).Debug()
 		if (fmod.mask & inodeMovedMask) > 0 {
@@ -881,11 +3033,51 @@ func (w *FileWatch) handleFileEvents(fmod fileMod, info os.FileInfo, fullPath st
 		} else if (fmod.mask & syscall.IN_ATTRIB) > 0 {
 			//attribute is changed
 			event = fileEventAttr
+			wasSymlink := fmod.finfo.FileMode&os.ModeSymlink != 0
+			isSymlink := info.Mode()&os.ModeSymlink != 0
+			modeChanged = info.Mode().Perm() != fmod.finfo.FileMode.Perm()
 			fmod.finfo.FileMode = info.Mode()
+			if uid, gid, ok := osutil.StatOwner(info); ok {
+				ownerChanged = uid != fmod.finfo.Uid || gid != fmod.finfo.Gid
+				fmod.finfo.Uid, fmod.finfo.Gid = uid, gid
+			}
+			switch {
+			case ownerChanged:
+				event = fileEventChown
+			case modeChanged:
+				event = fileEventChmod
+			}
+			// A file gaining Linux capabilities (security.capability xattr) can grant
+			// privileges without setuid, so flag it distinctly from a routine attribute change.
+			if caps, err := osutil.GetFileCapabilities(fullPath); err == nil {
+				capStr := strings.Join(caps, ",")
+				if capStr != "" && capStr != fmod.finfo.Capabilities {
+					event = fileEventCapability
+				}
+				fmod.finfo.Capabilities = capStr
+			}
+			// An attacker can swap a watched regular file for a symlink pointing
+			// outside the monitored tree to evade integrity checks (or swap a
+			// symlink back for a regular file to hide that it ever happened).
+			// This takes priority over the chmod/chown/capability classification
+			// above since it's the more consequential change.
+			if wasSymlink != isSymlink {
+				event = fileEventTypeChanged
+				if isSymlink {
+					if target, err := os.Readlink(fullPath); err == nil {
+						if filepath.IsAbs(target) {
+							target = filepath.Join(fmt.Sprintf("/proc/%d/root", pid), target)
+						} else {
+							target = filepath.Join(filepath.Dir(fullPath), target)
+						}
+						symlinkTarget = target
+					}
+				}
+			}
 		} else if (fmod.mask & (syscall.IN_ACCESS | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY)) > 0 {
 			// check the hash existing and match
 			event = fileEventAccessed
-			if hash, err := osutil.GetFileHash(fullPath); err == nil {
+			if hash, err := w.cachedFileHash(fullPath, info); err == nil {
 				if hash != fmod.finfo.Hash {
 					event = fileEventModified
 					fmod.finfo.Hash = hash
@@ -893,6 +3085,9 @@ func (w *FileWatch) handleFileEvents(fmod fileMod, info os.FileInfo, fullPath st
 			} else if (fmod.mask & syscall.IN_MODIFY) > 0 {
 				event = fileEventModified
 			}
+			if event == fileEventModified && w.captureDiff {
+				diff = w.captureDiffSnippet(fullPath)
+			}
 		} else {
 			log.WithFields(log.Fields{"fullPath": fullPath, "mask": fmod.mask}).Debug("file event not found")
 		}
@@ -906,26 +3101,31 @@ func (w *FileWatch) handleFileEvents(fmod fileMod, info os.FileInfo, fullPath st
 			w.removeFile(fullPath)
 		}
 	}
-	return event
+	return event, modeChanged, ownerChanged, symlinkTarget, diff
 }
 
 func (w *FileWatch) ContainerCleanup(rootPid int, bLeave bool) {
 	if !w.bEnable {
 		return
 	}
-	w.fanotifier.ContainerCleanup(rootPid)
-	w.inotifier.ContainerCleanup(rootPid)
+	if w.fanotifier != nil {
+		w.fanotifier.ContainerCleanup(rootPid)
+	}
+	if w.inotifier != nil {
+		w.inotifier.ContainerCleanup(rootPid)
+	}
 
 	w.mux.Lock()
 	defer w.mux.Unlock()
 	for path, _ := range w.fileEvents {
 		if pid, _ := global.SYS.ParseContainerFilePath(path); pid == rootPid {
-			delete( w.fileEvents, path)
+			delete(w.fileEvents, path)
 		}
 	}
 
 	if grp, ok := w.groups[rootPid]; ok {
 		if bLeave {
+			w.forgetContainerWatch(grp.cid)
 			delete(w.groups, rootPid)
 		} else {
 			// reset lists
@@ -935,21 +3135,266 @@ func (w *FileWatch) ContainerCleanup(rootPid int, bLeave bool) {
 	}
 }
 
+// GetWatchFileListPaged returns the [offset, offset+limit) slice of rootPid's
+// watched files -- sorted by path so pages are stable across calls -- along
+// with the total number of files matching pathPrefix, so a caller marshaling
+// this across the agent<->controller boundary doesn't have to pull an entire
+// big profile in one shot. pathPrefix filters to paths with that prefix;
+// pass "" to match all paths. limit<=0 means no limit.
+func (w *FileWatch) GetWatchFileListPaged(rootPid, offset, limit int, pathPrefix string) ([]*share.CLUSFileMonitorFile, int) {
+	if !w.bEnable || w.fanotifier == nil {
+		return nil, 0
+	}
+
+	all := w.fanotifier.GetWatchFileList(rootPid)
+	if pathPrefix != "" {
+		filtered := make([]*share.CLUSFileMonitorFile, 0, len(all))
+		for _, file := range all {
+			if strings.HasPrefix(file.Path, pathPrefix) {
+				filtered = append(filtered, file)
+			}
+		}
+		all = filtered
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Path < all[j].Path })
+
+	total := len(all)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return all[offset:end], total
+}
+
 func (w *FileWatch) GetWatchFileList(rootPid int) []*share.CLUSFileMonitorFile {
-	if !w.bEnable {
+	files, _ := w.GetWatchFileListPaged(rootPid, 0, 0, "")
+	return files
+}
+
+// FilterCoverage reports, for the container rooted at rootPid, which concrete
+// paths each configured filter resolved to and is currently watching. It's
+// meant for audit: proving that a filter -- e.g. one covering /etc/shadow --
+// is genuinely backed by an active fanotify mark, not just configured.
+func (w *FileWatch) FilterCoverage(rootPid int) map[string][]string {
+	if !w.bEnable || w.fanotifier == nil {
 		return nil
 	}
-	return w.fanotifier.GetWatchFileList(rootPid)
+	return w.fanotifier.FilterCoverage(rootPid)
 }
 
-func (w *FileWatch) GetAllFileMonitorFile() []*share.CLUSFileMonitorFile {
+// FilterStats is one filter's watch/event counts for a container, as
+// returned by GetFilterStats, keyed by filterIndexKey (e.g. "/etc/.*").
+type FilterStats struct {
+	FilterKey    string
+	WatchedPaths int
+	Events       int64
+}
+
+// GetFilterStats reports, per configured filter, how many concrete paths it
+// currently resolves to (via FilterCoverage) and how many events have been
+// reported against it since StartWatch, so a noisy profile can be tuned by
+// identifying which filter is responsible for most of the traffic.
+func (w *FileWatch) GetFilterStats(rootPid int) []FilterStats {
+	coverage := w.FilterCoverage(rootPid)
+
+	w.mux.Lock()
+	events := w.filterEvents[rootPid]
+	w.mux.Unlock()
+
+	keys := utils.NewSet()
+	for key := range coverage {
+		keys.Add(key)
+	}
+	for key := range events {
+		keys.Add(key)
+	}
+
+	stats := make([]FilterStats, 0, keys.Cardinality())
+	for itr := range keys.Iter() {
+		key := itr.(string)
+		stats = append(stats, FilterStats{
+			FilterKey:    key,
+			WatchedPaths: len(coverage[key]),
+			Events:       events[key],
+		})
+	}
+	return stats
+}
+
+// fileBaselineVersion is bumped whenever ExportedBaseline's shape changes, so
+// ImportBaseline can reject a document produced by an incompatible version
+// instead of silently misreading it.
+const fileBaselineVersion = 1
+
+// ExportedBaseline is the versioned JSON document produced by ExportBaseline
+// and consumed by ImportBaseline.
+type ExportedBaseline struct {
+	Version    int
+	RootPid    int
+	Fanotify   []BaselineEntry
+	Inotify    []string
+	ExportedAt time.Time
+}
+
+// ExportBaseline serializes rootPid's current in-memory baseline -- the
+// fanotify watch list with recorded hashes, plus the inotify watch list --
+// to JSON, for offline diagnosis of "why did this file alert" and for
+// ImportBaseline to reload.
+func (w *FileWatch) ExportBaseline(rootPid int) ([]byte, error) {
 	if !w.bEnable {
+		return nil, fmt.Errorf("FMON: file monitor is disabled")
+	}
+	w.mux.Lock()
+	_, ok := w.groups[rootPid]
+	w.mux.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("FMON: group not found, pid=%d", rootPid)
+	}
+
+	baseline := ExportedBaseline{
+		Version:    fileBaselineVersion,
+		RootPid:    rootPid,
+		ExportedAt: time.Now(),
+	}
+	if w.fanotifier != nil {
+		baseline.Fanotify = w.fanotifier.DumpBaseline(rootPid)
+	}
+	if w.inotifier != nil {
+		baseline.Inotify = w.inotifier.GetWatchFileList(rootPid)
+	}
+	return json.Marshal(&baseline)
+}
+
+// ImportBaseline parses a document previously produced by ExportBaseline,
+// e.g. to compare it against a live container's current baseline in a test,
+// or to inspect a baseline captured before a warm restart. It does not
+// re-establish the fanotify/inotify marks themselves -- doing so safely
+// requires walking the container's live filesystem again, which StartWatch
+// already does -- it only recovers the recorded data for comparison.
+func ImportBaseline(data []byte) (*ExportedBaseline, error) {
+	var baseline ExportedBaseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("FMON: failed to parse baseline: %v", err)
+	}
+	if baseline.Version != fileBaselineVersion {
+		return nil, fmt.Errorf("FMON: unsupported baseline version %d, expected %d", baseline.Version, fileBaselineVersion)
+	}
+	return &baseline, nil
+}
+
+// PathWatchInfo describes fsmon's current monitoring state for a single
+// path, as returned by DescribePath.
+type PathWatchInfo struct {
+	Path         string
+	RootPid      int
+	Covered      bool                         // a filter in the group's profile matches Path
+	Filter       *share.CLUSFileMonitorFilter // the matching filter, nil unless Covered
+	Fanotify     bool                         // actively watched by fanotify
+	Inotify      bool                         // actively watched by inotify
+	Protected    bool                         // block_access behavior: violations are denied, not just reported
+	BaselineHash string                       // hex-encoded baseline hash recorded at the last walk or resync, empty if unknown
+}
+
+// DescribePath reports fsmon's current monitoring state for containerRelPath
+// under rootPid: whether a filter covers it and which one, whether it's
+// actively watched by fanotify/inotify, whether it's protected (block
+// access), and its recorded baseline hash. It is purely introspective and
+// never modifies watch state, see FilterCoverage for the reverse query
+// (filter to paths).
+func (w *FileWatch) DescribePath(rootPid int, containerRelPath string) (*PathWatchInfo, error) {
+	w.mux.Lock()
+	grp, ok := w.groups[rootPid]
+	w.mux.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("FMON: container not found, rootPid=%d", rootPid)
+	}
+
+	info := &PathWatchInfo{Path: containerRelPath, RootPid: rootPid}
+	for _, filters := range [][]share.CLUSFileMonitorFilter{grp.profile.Filters, grp.profile.FiltersCRD} {
+		for _, flt := range filters {
+			if filterPathMatch(containerRelPath, flt) {
+				matched := flt
+				info.Covered = true
+				info.Filter = &matched
+				info.Protected = flt.Behavior == share.FileAccessBehaviorBlock
+				break
+			}
+		}
+		if info.Filter != nil {
+			break
+		}
+	}
+
+	if w.fanotifier != nil {
+		if finfo, _, protect, found := w.fanotifier.LookupPath(rootPid, containerRelPath); found {
+			info.Fanotify = true
+			info.Protected = info.Protected || protect
+			if finfo != nil && !osutil.HashZero(finfo.Hash) {
+				info.BaselineHash = fmt.Sprintf("%x", finfo.Hash)
+			}
+		}
+	}
+	if w.inotifier != nil {
+		info.Inotify = w.inotifier.IsWatched(fmt.Sprintf(procRootMountPoint, rootPid) + containerRelPath)
+	}
+
+	return info, nil
+}
+
+// ProtectFile escalates containerRelPath under rootPid to deny/permission
+// mode after it's already being watched, e.g. once tampering was detected
+// and the caller wants to block further access without rebuilding the
+// group's profile. containerRelPath must already be individually watched
+// (as by StartWatch/HandleWatchedFiles adding a filter match), not merely
+// covered as a member of a watched directory; an error is returned
+// otherwise. See DescribePath to check current watch/protect state first.
+func (w *FileWatch) ProtectFile(rootPid int, containerRelPath string) error {
+	if !w.bEnable || w.fanotifier == nil {
+		return fmt.Errorf("FMON: file monitor not enabled")
+	}
+	return w.fanotifier.ProtectFile(rootPid, containerRelPath)
+}
+
+func (w *FileWatch) GetAllFileMonitorFile() []*share.CLUSFileMonitorFile {
+	if !w.bEnable || w.fanotifier == nil {
 		return nil
 	}
 	return w.fanotifier.GetWatches()
 }
 
-////////
+// GetWatchesByContainer returns every currently watched file, grouped by the
+// container ID it belongs to, so a caller building a per-container
+// diagnostics view doesn't have to make one GetWatchFileList round-trip per
+// container or parse a rootPid out of /proc/<pid>/root prefixes itself.
+// Containers with no watched files are omitted.
+func (w *FileWatch) GetWatchesByContainer() map[string][]*share.CLUSFileMonitorFile {
+	if !w.bEnable || w.fanotifier == nil {
+		return nil
+	}
+
+	w.mux.Lock()
+	rootPids := make(map[int]string, len(w.groups))
+	for rootPid, grp := range w.groups {
+		rootPids[rootPid] = grp.cid
+	}
+	w.mux.Unlock()
+
+	byContainer := make(map[string][]*share.CLUSFileMonitorFile, len(rootPids))
+	for rootPid, cid := range rootPids {
+		if files := w.GetWatchFileList(rootPid); len(files) > 0 {
+			byContainer[cid] = files
+		}
+	}
+	return byContainer
+}
+
+// //////
 func (w *FileWatch) GetProbeData() *FmonProbeData {
 	var probeData FmonProbeData
 	if !w.bEnable {
@@ -959,8 +3404,22 @@ func (w *FileWatch) GetProbeData() *FmonProbeData {
 	w.mux.Lock()
 	probeData.NFileEvents = len(w.fileEvents)
 	probeData.NGroups = len(w.groups)
+	for _, grp := range w.groups {
+		if grp.paused {
+			probeData.NPausedGroups++
+		}
+	}
+	probeData.NDroppedEvents = w.droppedEvents
 	w.mux.Unlock()
 
+	w.containerWatchMu.Lock()
+	for _, truncated := range w.containerTruncated {
+		if truncated {
+			probeData.NTruncatedGroups++
+		}
+	}
+	w.containerWatchMu.Unlock()
+
 	if w.fanotifier != nil {
 		w.fanotifier.GetProbeData(&probeData.Fan)
 	}
@@ -972,26 +3431,87 @@ func (w *FileWatch) GetProbeData() *FmonProbeData {
 	return &probeData
 }
 
-func (w *FileWatch) SetMonitorTrace(bEnable bool) {
+// ExportMetrics returns a Prometheus-friendly snapshot of file-monitor
+// health, derived from GetProbeData plus the cumulative counters tracked
+// since startup. It is safe to call concurrently with the watcher loop.
+func (w *FileWatch) ExportMetrics() map[string]float64 {
+	metrics := map[string]float64{
+		"total_events_processed": float64(atomic.LoadInt64(&w.totalEventsProcessed)),
+		"total_reports_sent":     float64(atomic.LoadInt64(&w.totalReportsSent)),
+	}
+
+	probeData := w.GetProbeData()
+	if probeData == nil {
+		return metrics
+	}
+	metrics["n_file_events"] = float64(probeData.NFileEvents)
+	metrics["n_dropped_events"] = float64(probeData.NDroppedEvents)
+	metrics["n_groups"] = float64(probeData.NGroups)
+	metrics["fanotify_n_roots"] = float64(probeData.Fan.NRoots)
+	metrics["fanotify_n_dir_marks"] = float64(probeData.Fan.NDirMarks)
+	metrics["fanotify_n_rules"] = float64(probeData.Fan.NRules)
+	metrics["inotify_n_wds"] = float64(probeData.Ino.NWds)
+	return metrics
+}
+
+// SetMonitorTrace independently toggles trace-level logging for the monitor
+// package itself and its fanotify/inotify notifiers, e.g. to enable verbose
+// fanotify tracing without also drowning in inotify debug lines. It's called
+// concurrently with the running loop() and notifier goroutines that log
+// through mLog/faLog/inLog, so it goes through Logger.SetLevel -- backed by
+// an atomic store -- rather than assigning the exported Level field
+// directly, which raced under -race.
+func (w *FileWatch) SetMonitorTrace(monitor, fanotify, inotify bool) {
+	setLoggerLevel(mLog, monitor)
+	setLoggerLevel(faLog, fanotify)
+	setLoggerLevel(inLog, inotify)
+}
+
+func setLoggerLevel(l *log.Logger, bEnable bool) {
 	if bEnable {
-		mLog.Level = log.DebugLevel
+		l.SetLevel(log.DebugLevel)
 	} else {
-		mLog.Level = log.InfoLevel
+		l.SetLevel(log.InfoLevel)
 	}
 }
 
-//////////////////////
+// ////////////////////
 const (
 	dirIterTimeout  = time.Second * 8
 	rootIterTimeout = time.Second * 16
 )
 
-// generic get a directory file list
-func (w *FileWatch) getDirFileList(pid int, base, regexStr, cid string, flt interface{}, recur, protect, userAdded bool) (map[string]*osutil.FileInfoExt, []*osutil.FileInfoExt) {
+// relPathDepth reports how many directory levels rel -- a path already
+// relative to some base, e.g. a workerlet.DirData.Dir or FileData.File --
+// sits below that base: 0 for the base itself, 1 for a direct child, and so
+// on. See CLUSFileMonitorFilter.MaxDepth.
+func relPathDepth(rel string) int {
+	rel = strings.Trim(rel, "/")
+	if rel == "" {
+		return 0
+	}
+	return strings.Count(rel, "/") + 1
+}
+
+// filterDepth is relPathDepth for rel expressed as a full path rather than
+// one already relative to base.
+func filterDepth(base, rel string) int {
+	return relPathDepth(strings.TrimPrefix(rel, strings.TrimSuffix(base, "/")))
+}
+
+// generic get a directory file list. rootWalkTimeout overrides rootIterTimeout
+// for the base=="" (root filesystem) case when non-zero, see
+// FsmonConfig.WalkTimeout.
+func (w *FileWatch) getDirFileList(pid int, base, regexStr, cid string, flt interface{}, recur, protect, userAdded bool, rootWalkTimeout time.Duration) (map[string]*osutil.FileInfoExt, []*osutil.FileInfoExt) {
 	if !w.bEnable {
 		return nil, nil
 	}
 
+	maxDepth := 0
+	if fr, ok := flt.(*filterRegex); ok {
+		maxDepth = fr.maxDepth
+	}
+
 	dirList := make(map[string]*osutil.FileInfoExt)
 	singleFiles := make([]*osutil.FileInfoExt, 0)
 
@@ -999,6 +3519,9 @@ func (w *FileWatch) getDirFileList(pid int, base, regexStr, cid string, flt inte
 	if base == "" {
 		base += "/"
 		tmOut = rootIterTimeout
+		if rootWalkTimeout > 0 {
+			tmOut = rootWalkTimeout
+		}
 	}
 	base = strings.Replace(base, "\\.", ".", -1)
 	dirs := utils.NewSet(base)
@@ -1046,13 +3569,27 @@ func (w *FileWatch) getDirFileList(pid int, base, regexStr, cid string, flt inte
 			Timeout: tmOut,
 		}
 
+		acquireCtx, cancel := context.WithTimeout(context.Background(), w.walkAcquireTimeout)
+		acquireErr := w.walkerLimiter.Acquire(acquireCtx, 1)
+		cancel()
+		if acquireErr != nil {
+			log.WithFields(log.Fields{"req": req, "regexStr": regexStr, "any": any}).Warn("FMON: walker busy, skipping walk")
+			dirs.Remove(any)
+			continue
+		}
+
 		bytesValue, _, err := w.walkerTask.RunWithTimeout(req, cid, req.Timeout)
+		w.walkerLimiter.Release(1)
 		if err == nil {
 			err = json.Unmarshal(bytesValue, &res)
 		}
 
 		if err != nil {
-			log.WithFields(log.Fields{"req": req, "error": err, "regexStr": regexStr, "any": any}).Error()
+			if strings.Contains(err.Error(), "timeout") {
+				log.WithFields(log.Fields{"req": req, "regexStr": regexStr, "any": any}).Warn("FMON: walk timed out")
+			} else {
+				log.WithFields(log.Fields{"req": req, "error": err, "regexStr": regexStr, "any": any}).Error()
+			}
 			dirs.Remove(any)
 			continue
 		}
@@ -1060,6 +3597,10 @@ func (w *FileWatch) getDirFileList(pid int, base, regexStr, cid string, flt inte
 		for _, d := range res.Dirs {
 			path := filepath.Join(realPath, d.Dir)
 			if realPath != path && regexStr == ".*" {
+				if maxDepth > 0 && relPathDepth(d.Dir) > maxDepth {
+					log.WithFields(log.Fields{"path": path, "maxDepth": maxDepth}).Debug("FMON: recursive filter depth limit reached, skipping dir")
+					continue
+				}
 				// log.WithFields(log.Fields{"dir": path}).Debug()
 				dinfo := &osutil.FileInfoExt{
 					FileMode:  finfo.Mode(), // ??
@@ -1077,6 +3618,10 @@ func (w *FileWatch) getDirFileList(pid int, base, regexStr, cid string, flt inte
 			if !recur && realPath != filepath.Dir(path) {
 				continue
 			}
+			if maxDepth > 0 && relPathDepth(f.File) > maxDepth {
+				log.WithFields(log.Fields{"path": path, "maxDepth": maxDepth}).Debug("FMON: recursive filter depth limit reached, skipping file")
+				continue
+			}
 
 			fstr := fmt.Sprintf("%s/%s", filepath.Dir(path), regexStr)
 			regx, err := regexp.Compile(fmt.Sprintf("^%s$", fstr))
@@ -0,0 +1,43 @@
+package fsmon
+
+import (
+	"os"
+	"testing"
+
+	"github.com/neuvector/neuvector/share/global"
+	"github.com/neuvector/neuvector/share/system"
+)
+
+// TestDetectNewMountNamespaces covers detectNewMountNamespaces: a process
+// running in a mount namespace other than the group's root one is reported
+// once as a new-namespace event, and a repeat sighting of the same namespace
+// is suppressed by grp.seenMntNs.
+func TestDetectNewMountNamespaces(t *testing.T) {
+	if global.SYS == nil {
+		global.SYS = system.NewSystemTools()
+	}
+	selfNs := global.SYS.GetMntNamespaceId(os.Getpid())
+	if selfNs == 0 {
+		t.Skip("unable to read this process's mount namespace in this environment")
+	}
+
+	w := &FileWatch{eventIDBucket: 0}
+	grp := &groupInfo{
+		rootMntNs: selfNs + 1, // any value different from selfNs simulates a distinct root namespace
+		seenMntNs: make(map[uint64]bool),
+	}
+	pInfo := []*ProcInfo{{Pid: os.Getpid(), Name: "self", Path: "/proc/self"}}
+
+	msgs := w.detectNewMountNamespaces(grp, "container1", pInfo)
+	if len(msgs) != 1 {
+		t.Fatalf("expected exactly one new-mount-namespace notice, got %d", len(msgs))
+	}
+	if msgs[0].ProcPid != os.Getpid() {
+		t.Errorf("expected the notice to reference pid %d, got %d", os.Getpid(), msgs[0].ProcPid)
+	}
+
+	// A second sighting of the same namespace must not be reported again.
+	if msgs := w.detectNewMountNamespaces(grp, "container1", pInfo); len(msgs) != 0 {
+		t.Errorf("expected a repeat sighting of the same namespace to be suppressed, got %d", len(msgs))
+	}
+}
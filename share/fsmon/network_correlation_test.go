@@ -0,0 +1,48 @@
+package fsmon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neuvector/neuvector/share"
+)
+
+// TestSendMsgNetworkCorrelated covers FileMonitorConfig.NetworkActivityHint:
+// sendMsg must flag MonitorMessage.NetworkCorrelated when the hint reports
+// outbound activity within networkCorrelationWindow, and leave it unset when
+// the last activity is older than that window or the hint has none at all.
+func TestSendMsgNetworkCorrelated(t *testing.T) {
+	newWatch := func(hint NetworkActivityHint) *FileWatch {
+		return &FileWatch{
+			bEnable:             true,
+			eventIDBucket:       time.Second,
+			estRuleSrc:          func(id, path string, bBlocked bool) string { return "" },
+			networkActivityHint: hint,
+			topPathCounts:       make(map[string]int64),
+		}
+	}
+	lastMsg := func(w *FileWatch) *MonitorMessage {
+		var msg *MonitorMessage
+		w.sendrpt = func(m *MonitorMessage) bool {
+			msg = m
+			return true
+		}
+		w.sendMsg(1, "container1", "/tmp/a", fileEventModified, nil, share.PolicyModeEvaluate, false, "", false, false, false, false, "", "")
+		return msg
+	}
+
+	w := newWatch(func(rootPid int) time.Time { return time.Now().Add(-time.Second) })
+	if msg := lastMsg(w); !msg.NetworkCorrelated {
+		t.Error("expected NetworkCorrelated to be set for a hint inside networkCorrelationWindow")
+	}
+
+	wStale := newWatch(func(rootPid int) time.Time { return time.Now().Add(-networkCorrelationWindow * 2) })
+	if msg := lastMsg(wStale); msg.NetworkCorrelated {
+		t.Error("expected NetworkCorrelated to be unset for a hint outside networkCorrelationWindow")
+	}
+
+	wNoHint := newWatch(nil)
+	if msg := lastMsg(wNoHint); msg.NetworkCorrelated {
+		t.Error("expected NetworkCorrelated to be unset when no NetworkActivityHint is configured")
+	}
+}
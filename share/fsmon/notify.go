@@ -8,9 +8,11 @@ import (
 type NotifyCallback func(path string, mask uint32, params interface{}, pInfo *ProcInfo)
 
 type filterRegex struct {
-	path  string
-	regex *regexp.Regexp
+	path      string
+	regex     *regexp.Regexp
 	recursive bool
+	base      string // filter's configured base directory, see CLUSFileMonitorFilter.MaxDepth
+	maxDepth  int    // CLUSFileMonitorFilter.MaxDepth, 0 means unlimited
 }
 
 type IFile struct {
@@ -25,7 +27,7 @@ type IFile struct {
 	protect bool // access control
 	learnt  bool // discover mode
 	userAdd bool
-	lastChg int64	// unix time
+	lastChg int64 // unix time
 }
 
 type fNotify struct {
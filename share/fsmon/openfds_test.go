@@ -0,0 +1,38 @@
+package fsmon
+
+import (
+	"os"
+	"testing"
+)
+
+// TestCaptureOpenFDs covers captureOpenFDs: a process holding several files
+// open must have all of their paths captured from /proc/<pid>/fd.
+func TestCaptureOpenFDs(t *testing.T) {
+	const numFiles = 3
+	var files []*os.File
+	var wantPaths []string
+	for i := 0; i < numFiles; i++ {
+		f, err := os.CreateTemp("", "openfd")
+		if err != nil {
+			t.Fatalf("CreateTemp failed: %v", err)
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		files = append(files, f)
+		wantPaths = append(wantPaths, f.Name())
+	}
+
+	got := captureOpenFDs(os.Getpid())
+	if got == nil {
+		t.Fatal("expected captureOpenFDs to return the process's open file paths, got nil")
+	}
+	gotSet := make(map[string]bool, len(got))
+	for _, p := range got {
+		gotSet[p] = true
+	}
+	for _, want := range wantPaths {
+		if !gotSet[want] {
+			t.Errorf("expected %q to be among the captured open FDs", want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package fsmon
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeCharDevInfo is a minimal os.FileInfo implementation for exercising
+// isOverlayWhiteout without needing an actual mknod'd device file.
+type fakeCharDevInfo struct {
+	mode os.FileMode
+	rdev uint64
+}
+
+func (f fakeCharDevInfo) Name() string       { return "whiteout" }
+func (f fakeCharDevInfo) Size() int64        { return 0 }
+func (f fakeCharDevInfo) Mode() os.FileMode  { return f.mode }
+func (f fakeCharDevInfo) ModTime() time.Time { return time.Time{} }
+func (f fakeCharDevInfo) IsDir() bool        { return false }
+func (f fakeCharDevInfo) Sys() interface{}   { return &syscall.Stat_t{Rdev: f.rdev} }
+
+// TestIsOverlayWhiteout covers isOverlayWhiteout: only a char device with
+// major/minor 0/0 counts as an overlayfs whiteout marker.
+func TestIsOverlayWhiteout(t *testing.T) {
+	if !isOverlayWhiteout(fakeCharDevInfo{mode: os.ModeCharDevice, rdev: 0}) {
+		t.Error("expected a 0/0 char device to be recognized as a whiteout")
+	}
+	if isOverlayWhiteout(fakeCharDevInfo{mode: os.ModeCharDevice, rdev: 1}) {
+		t.Error("expected a char device with a nonzero rdev to not be a whiteout")
+	}
+	if isOverlayWhiteout(fakeCharDevInfo{mode: 0, rdev: 0}) {
+		t.Error("expected a regular file to not be a whiteout")
+	}
+	if isOverlayWhiteout(nil) {
+		t.Error("expected a nil FileInfo to not be a whiteout")
+	}
+}
+
+// TestHandleDirEventsWhiteoutReportsRemoval covers handleDirEvents: the
+// creation of an overlayfs whiteout marker in place of a shadowed file must
+// be reported as its removal, not as a file create.
+func TestHandleDirEventsWhiteoutReportsRemoval(t *testing.T) {
+	w := &FileWatch{}
+	fmod := fileMod{mask: syscall.IN_CREATE}
+	info := fakeCharDevInfo{mode: os.ModeCharDevice, rdev: 0}
+
+	event := w.handleDirEvents(fmod, info, "/host/merged/upper/shadowed", "shadowed", 0)
+	if event != fileEventRemoved {
+		t.Errorf("expected a whiteout creation to be reported as fileEventRemoved, got %d", event)
+	}
+}
@@ -0,0 +1,49 @@
+package fsmon
+
+import (
+	"testing"
+	"time"
+)
+
+// TestObservePathRateAnomaly covers observePathRate's adaptive throttling:
+// once a path's learned baseline is established, a window whose event count
+// spikes past baseline*throttleFactor is flagged anomalous, while a window
+// that merely repeats the established rate is not.
+func TestObservePathRateAnomaly(t *testing.T) {
+	w := &FileWatch{
+		rateStats:      make(map[string]*pathRateStat),
+		throttleFactor: defaultThrottleFactor,
+		throttleWindow: defaultThrottleWindow,
+	}
+	const path = "/tmp/watched-file"
+
+	// Roll a few windows of 3 events each to establish a baseline, backdating
+	// windowStart directly (rather than sleeping) so each observePathRate
+	// call sees its window as already elapsed.
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if anomalous := w.observePathRate(path); anomalous {
+				t.Fatalf("did not expect an anomaly while establishing the baseline")
+			}
+		}
+		w.rateStats[path].windowStart = time.Now().Add(-2 * w.throttleWindow)
+	}
+
+	if baseline := w.rateStats[path].baseline; baseline <= 0 {
+		t.Fatalf("expected a positive learned baseline, got %v", baseline)
+	}
+
+	// Spike well past baseline*throttleFactor within the new window.
+	var anomalous bool
+	for i := 0; i < 50; i++ {
+		anomalous = w.observePathRate(path)
+	}
+	if !anomalous {
+		t.Error("expected observePathRate to flag the spike as anomalous")
+	}
+
+	// A fresh path with no baseline yet must never be flagged.
+	if w.observePathRate("/tmp/other-file") {
+		t.Error("expected observePathRate to not flag a path with no learned baseline")
+	}
+}
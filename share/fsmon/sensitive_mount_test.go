@@ -0,0 +1,63 @@
+package fsmon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neuvector/neuvector/share"
+)
+
+// TestPathCriticalSensitiveMount covers pathCritical against
+// FsmonConfig.SensitiveMounts, and TestSendMsgCriticalMountEvent covers the
+// resulting event: a write under a container's mounted runtime socket must
+// be flagged Critical with VulnSeverityCritical severity and the
+// criticalMountMarker note, unlike a write to an ordinary path.
+func TestPathCriticalSensitiveMount(t *testing.T) {
+	mounts := []string{"/var/run/docker.sock"}
+
+	if !pathCritical(mounts, "/var/run/docker.sock") {
+		t.Error("expected the mount itself to be critical")
+	}
+	if !pathCritical(mounts, "/var/run/docker.sock/subpath") {
+		t.Error("expected a path under the mount to be critical")
+	}
+	if pathCritical(mounts, "/var/run/other.sock") {
+		t.Error("expected an unrelated path to not be critical")
+	}
+}
+
+func TestSendMsgCriticalMountEvent(t *testing.T) {
+	mounts := []string{"/var/run/docker.sock"}
+	w := &FileWatch{
+		bEnable:       true,
+		eventIDBucket: time.Second,
+		estRuleSrc:    func(id, path string, bBlocked bool) string { return "" },
+		topPathCounts: make(map[string]int64),
+	}
+
+	var msg *MonitorMessage
+	w.sendrpt = func(m *MonitorMessage) bool {
+		msg = m
+		return true
+	}
+
+	critical := pathCritical(mounts, "/var/run/docker.sock")
+	w.sendMsg(1, "container1", "/var/run/docker.sock", fileEventModified, nil, share.PolicyModeEvaluate, false, "", critical, false, false, false, "", "")
+
+	if !msg.Critical {
+		t.Error("expected a write to a sensitive mount to be flagged Critical")
+	}
+	if msg.Severity != share.VulnSeverityCritical {
+		t.Errorf("expected severity %q, got %q", share.VulnSeverityCritical, msg.Severity)
+	}
+	if !strings.Contains(msg.Msg, criticalMountMarker) {
+		t.Errorf("expected message to carry the critical mount marker, got %q", msg.Msg)
+	}
+
+	msg = nil
+	w.sendMsg(1, "container1", "/tmp/ordinary", fileEventModified, nil, share.PolicyModeEvaluate, false, "", false, false, false, false, "", "")
+	if msg.Critical {
+		t.Error("expected an ordinary path to not be flagged Critical")
+	}
+}
@@ -0,0 +1,71 @@
+package fsmon
+
+import (
+	"os"
+	"testing"
+
+	"github.com/neuvector/neuvector/share/osutil"
+)
+
+// TestAddCoreFileAdmitsByPriorityUnderBudget covers the node-wide watch
+// budget's priority admission: when the budget can't fit every candidate,
+// addCoreFile must admit the highest-priority ones -- protected filters
+// first, then ordinary single-file filters -- ahead of broad recursive
+// filters, which are dropped instead.
+func TestAddCoreFileAdmitsByPriorityUnderBudget(t *testing.T) {
+	in, err := NewInotify()
+	if err != nil {
+		t.Fatalf("NewInotify failed: %v", err)
+	}
+	defer os.Remove(in.inotifyFile.Name())
+	defer in.inotifyFile.Close()
+
+	makeTempFile := func() string {
+		f, err := os.CreateTemp("", "watchbudget")
+		if err != nil {
+			t.Fatalf("CreateTemp failed: %v", err)
+		}
+		f.Close()
+		t.Cleanup(func() { os.Remove(f.Name()) })
+		return f.Name()
+	}
+
+	protectedFile := &osutil.FileInfoExt{
+		Path:    makeTempFile(),
+		Protect: true,
+		Filter:  &filterRegex{path: "/etc/shadow"},
+	}
+	ordinaryFile := &osutil.FileInfoExt{
+		Path:   makeTempFile(),
+		Filter: &filterRegex{path: "/etc/passwd"},
+	}
+	recursiveFile := &osutil.FileInfoExt{
+		Path:   makeTempFile(),
+		Filter: &filterRegex{path: "/var/log/*", recursive: true},
+	}
+
+	w := &FileWatch{
+		bEnable:     true,
+		watchBudget: 2,
+		inotifier:   in,
+	}
+
+	// Deliberately listed low-priority first, to prove admission order comes
+	// from watchPriority, not input order.
+	singleFiles := []*osutil.FileInfoExt{recursiveFile, ordinaryFile, protectedFile}
+
+	w.addCoreFile(true, "container1", map[string]*osutil.FileInfoExt{}, singleFiles)
+
+	if w.watchCount != 2 {
+		t.Fatalf("expected the budget to admit exactly 2 watches, got %d", w.watchCount)
+	}
+	if !in.IsWatched(protectedFile.Path) {
+		t.Errorf("expected the protected filter to be admitted first")
+	}
+	if !in.IsWatched(ordinaryFile.Path) {
+		t.Errorf("expected the ordinary filter to be admitted second")
+	}
+	if in.IsWatched(recursiveFile.Path) {
+		t.Errorf("expected the recursive filter to be dropped once the budget is exhausted")
+	}
+}
@@ -0,0 +1,49 @@
+package osutil
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// TestGetFileCapabilities covers decoding the security.capability xattr:
+// a file with cap_net_raw+ep set must report CAP_NET_RAW, and a file with no
+// xattr at all must report no capabilities without error.
+func TestGetFileCapabilities(t *testing.T) {
+	f, err := os.CreateTemp("", "capfile")
+	if err != nil {
+		t.Fatalf("CreateTemp failed: %v", err)
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	if caps, err := GetFileCapabilities(path); err != nil || len(caps) != 0 {
+		t.Fatalf("expected no capabilities on a plain file, got %v, err %v", caps, err)
+	}
+
+	// VFS_CAP_REVISION_2 security.capability xattr granting only CAP_NET_RAW
+	// (bit 13) as permitted, mirroring what `setcap cap_net_raw+ep` writes.
+	buf := make([]byte, 20)
+	binary.LittleEndian.PutUint32(buf[0:4], 0x02000001)
+	binary.LittleEndian.PutUint32(buf[4:8], 1<<13)
+	if err := unix.Lsetxattr(path, "security.capability", buf, 0); err != nil {
+		t.Skipf("Lsetxattr not permitted in this environment: %v", err)
+	}
+
+	caps, err := GetFileCapabilities(path)
+	if err != nil {
+		t.Fatalf("GetFileCapabilities failed: %v", err)
+	}
+	found := false
+	for _, c := range caps {
+		if c == "CAP_NET_RAW" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected CAP_NET_RAW in decoded capabilities, got %v", caps)
+	}
+}
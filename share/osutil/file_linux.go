@@ -1,16 +1,21 @@
 package osutil
 
 import (
+	"crypto/md5"
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
 
 	"github.com/neuvector/neuvector/share/global"
 	"github.com/neuvector/neuvector/share/utils"
@@ -22,6 +27,31 @@ const (
 	fileHashKeep      = 8
 )
 
+// Supported values for SetFileHashAlgorithm. The zero value ("") keeps the
+// original behavior: a sha256 digest of only the first fileSizeHashLimit
+// bytes, truncated to fileHashKeep bytes.
+const (
+	HashAlgoMD5    = "md5"
+	HashAlgoSHA256 = "sha256"
+)
+
+// hashAlgorithm selects the integrity-hash algorithm used by GetFileHash.
+// HashAlgoSHA256 hashes the whole file (not just the first chunk), which is
+// what FIPS compliance baselines require.
+var hashAlgorithm = ""
+
+// SetFileHashAlgorithm selects the algorithm used by subsequent GetFileHash
+// calls ("md5" or "sha256"). An unrecognized value restores the default,
+// pre-existing behavior.
+func SetFileHashAlgorithm(algo string) {
+	switch algo {
+	case HashAlgoMD5, HashAlgoSHA256:
+		hashAlgorithm = algo
+	default:
+		hashAlgorithm = ""
+	}
+}
+
 // true is package file, to trigger re-scan
 var packageFiles utils.Set = utils.NewSet(
 	"/var/lib/dpkg/status",
@@ -31,16 +61,33 @@ var packageFiles utils.Set = utils.NewSet(
 )
 
 type FileInfoExt struct {
-	ContainerId string
-	Path        string
-	Link        string
-	FileMode    os.FileMode
-	Size        int64
-	Hash        [fileHashKeep]byte
-	Filter      interface{}
-	Children    []*FileInfoExt
-	Protect     bool
-	UserAdded   bool
+	ContainerId  string
+	Path         string
+	Link         string
+	FileMode     os.FileMode
+	Uid          uint32 // owning uid last observed on this path, see StatOwner
+	Gid          uint32 // owning gid last observed on this path, see StatOwner
+	Size         int64
+	Hash         [fileHashKeep]byte
+	Filter       interface{}
+	Children     []*FileInfoExt
+	Protect      bool
+	UserAdded    bool
+	Capabilities string // comma-separated names of Linux file capabilities last observed on this path, empty if none
+}
+
+// StatOwner extracts the owning uid/gid from an os.FileInfo obtained via
+// os.Lstat/os.Stat on Linux. ok is false if info is nil or its underlying
+// Sys() isn't the expected *syscall.Stat_t, e.g. on a non-Linux GOOS.
+func StatOwner(info os.FileInfo) (uid, gid uint32, ok bool) {
+	if info == nil {
+		return 0, 0, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return st.Uid, st.Gid, true
 }
 
 func fileExists(path string) bool {
@@ -76,7 +123,7 @@ func extractProcRootPath(pid int, input string, inTest bool) (string, error) {
 		}
 		return matches[0], nil
 	} else {
-		return fmt.Sprintf(global.SYS.GetProcDir() + "%d/root", pid), nil
+		return fmt.Sprintf(global.SYS.GetProcDir()+"%d/root", pid), nil
 	}
 }
 
@@ -167,6 +214,20 @@ func GetExePathFromLink(pid int) (string, error) {
 }
 
 func GetFileHash(filepath string) ([fileHashKeep]byte, error) {
+	if hashAlgorithm == HashAlgoSHA256 {
+		// FIPS baseline: hash the entire file rather than just the first chunk.
+		f, err := os.Open(filepath)
+		if err != nil {
+			return [fileHashKeep]byte{}, err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return [fileHashKeep]byte{}, err
+		}
+		return truncateHash(h.Sum(nil)), nil
+	}
+
 	//only hash the first 16k, if the file is too large
 	buf := make([]byte, fileSizeHashLimit)
 	f, err := os.Open(filepath)
@@ -175,15 +236,15 @@ func GetFileHash(filepath string) ([fileHashKeep]byte, error) {
 	}
 	defer f.Close()
 	if n, err := f.Read(buf); err == nil {
-		sh := sha256.Sum256(buf[:n])
-		var ha [fileHashKeep]byte
-		for i, v := range sh {
-			if i >= fileHashKeep {
-				break
-			}
-			ha[i] = v
+		var sum []byte
+		if hashAlgorithm == HashAlgoMD5 {
+			s := md5.Sum(buf[:n])
+			sum = s[:]
+		} else {
+			s := sha256.Sum256(buf[:n])
+			sum = s[:]
 		}
-		return ha, nil
+		return truncateHash(sum), nil
 	} else {
 		if err.Error() == "EOF" { // it is there but an empty file
 			err = nil
@@ -192,6 +253,107 @@ func GetFileHash(filepath string) ([fileHashKeep]byte, error) {
 	}
 }
 
+func truncateHash(sum []byte) [fileHashKeep]byte {
+	var ha [fileHashKeep]byte
+	for i, v := range sum {
+		if i >= fileHashKeep {
+			break
+		}
+		ha[i] = v
+	}
+	return ha
+}
+
+// vfsCapData mirrors the on-disk layout of struct vfs_cap_data (linux/capability.h):
+// a magic_etc word (revision + flags) followed by up to two permitted/inheritable
+// 32-bit halves.
+const (
+	vfsCapRevisionMask = 0xFF000000
+	vfsCapRevision2    = 0x02000000
+	vfsCapRevision3    = 0x03000000
+)
+
+// capabilityNames maps a CAP_* bit position (linux/capability.h) to its name.
+var capabilityNames = map[uint]string{
+	0:  "CAP_CHOWN",
+	1:  "CAP_DAC_OVERRIDE",
+	2:  "CAP_DAC_READ_SEARCH",
+	3:  "CAP_FOWNER",
+	4:  "CAP_FSETID",
+	5:  "CAP_KILL",
+	6:  "CAP_SETGID",
+	7:  "CAP_SETUID",
+	8:  "CAP_SETPCAP",
+	9:  "CAP_LINUX_IMMUTABLE",
+	10: "CAP_NET_BIND_SERVICE",
+	11: "CAP_NET_BROADCAST",
+	12: "CAP_NET_ADMIN",
+	13: "CAP_NET_RAW",
+	14: "CAP_IPC_LOCK",
+	15: "CAP_IPC_OWNER",
+	16: "CAP_SYS_MODULE",
+	17: "CAP_SYS_RAWIO",
+	18: "CAP_SYS_CHROOT",
+	19: "CAP_SYS_PTRACE",
+	20: "CAP_SYS_PACCT",
+	21: "CAP_SYS_ADMIN",
+	22: "CAP_SYS_BOOT",
+	23: "CAP_SYS_NICE",
+	24: "CAP_SYS_RESOURCE",
+	25: "CAP_SYS_TIME",
+	26: "CAP_SYS_TTY_CONFIG",
+	27: "CAP_MKNOD",
+	28: "CAP_LEASE",
+	29: "CAP_AUDIT_WRITE",
+	30: "CAP_AUDIT_CONTROL",
+	31: "CAP_SETFCAP",
+	32: "CAP_MAC_OVERRIDE",
+	33: "CAP_MAC_ADMIN",
+	34: "CAP_SYSLOG",
+	35: "CAP_WAKE_ALARM",
+	36: "CAP_BLOCK_SUSPEND",
+	37: "CAP_AUDIT_READ",
+	38: "CAP_PERFMON",
+	39: "CAP_BPF",
+	40: "CAP_CHECKPOINT_RESTORE",
+}
+
+// GetFileCapabilities returns the decoded names of the Linux file capabilities
+// (the POSIX capability set stored in the security.capability xattr) granted
+// to filepath. It returns a nil slice, with no error, when the file has no
+// capabilities set.
+func GetFileCapabilities(filepath string) ([]string, error) {
+	buf := make([]byte, 32)
+	n, err := unix.Lgetxattr(filepath, "security.capability", buf)
+	if err != nil {
+		if errors.Is(err, unix.ENODATA) || errors.Is(err, unix.ENOTSUP) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return decodeVfsCapData(buf[:n]), nil
+}
+
+func decodeVfsCapData(buf []byte) []string {
+	if len(buf) < 8 {
+		return nil
+	}
+	magic := binary.LittleEndian.Uint32(buf[0:4])
+	permitted := uint64(binary.LittleEndian.Uint32(buf[4:8]))
+	if revision := magic & vfsCapRevisionMask; (revision == vfsCapRevision2 || revision == vfsCapRevision3) && len(buf) >= 16 {
+		permitted |= uint64(binary.LittleEndian.Uint32(buf[12:16])) << 32
+	}
+
+	var caps []string
+	for bit, name := range capabilityNames {
+		if permitted&(1<<bit) != 0 {
+			caps = append(caps, name)
+		}
+	}
+	sort.Strings(caps)
+	return caps
+}
+
 func IsPackageLib(path string) bool {
 	return packageFiles.Contains(path)
 }
@@ -205,7 +367,7 @@ func GetFileInfoExtFromPid(root, pid int) []*FileInfoExt {
 	}
 }
 
-//get the file information, if the file is a symlink, return both the symlink and the real file
+// get the file information, if the file is a symlink, return both the symlink and the real file
 func GetFileInfoExtFromPath(root int, path string, flt interface{}, protect, userAdded bool) []*FileInfoExt {
 	files := make([]*FileInfoExt, 0)
 	if info, err := os.Lstat(path); err == nil {
@@ -217,6 +379,7 @@ func GetFileInfoExtFromPath(root int, path string, flt interface{}, protect, use
 			Protect:   protect,
 			UserAdded: userAdded,
 		}
+		finfo.Uid, finfo.Gid, _ = StatOwner(info)
 		//for symlink, we need to watch two of them, symlink and the real file
 		//read the link and create a seperated file info.
 		if (finfo.FileMode & os.ModeSymlink) != 0 {
@@ -230,6 +393,7 @@ func GetFileInfoExtFromPath(root int, path string, flt interface{}, protect, use
 				if info, err := os.Lstat(rpath); err == nil {
 					rinfo.FileMode = info.Mode()
 					rinfo.Size = info.Size()
+					rinfo.Uid, rinfo.Gid, _ = StatOwner(info)
 					if rinfo.Hash, err = GetFileHash(rpath); err == nil {
 						files = append(files, rinfo)
 					}
@@ -281,7 +445,7 @@ func GetFileDirInfo(file string) FileInfoExt {
 	return FileInfoExt{}
 }
 
-/////////
+// ///////
 const ErrorNotDirectory string = "src is not a directory"
 
 // CopyFile copies the contents of the file named src to the file named
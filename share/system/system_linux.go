@@ -59,6 +59,28 @@ type SystemTools struct {
 	clockTicksPerSecond uint64
 	cgroupVersion       int
 	cgroupMemoryDir     string
+	pathResolver        ContainerPathResolver
+}
+
+// ContainerPathResolver maps between a container-relative path and the
+// pid-scoped, host-visible path used to reach it. The default behavior
+// (used when no resolver is set) assumes a /proc/<pid>/root layout, which
+// does not hold for every runtime (e.g. gVisor, Kata). Runtimes that expose
+// the container root differently can install their own resolver via
+// SystemTools.SetContainerPathResolver.
+type ContainerPathResolver interface {
+	// ContainerFilePath returns the host-visible path for pid's container-relative path.
+	ContainerFilePath(pid int, path string) string
+	// ParseContainerFilePath extracts the pid and container-relative path out of
+	// a host-visible path produced by ContainerFilePath.
+	ParseContainerFilePath(path string) (int, string)
+}
+
+// SetContainerPathResolver installs an alternate strategy for mapping between
+// container-relative and host-visible paths. Passing nil restores the default
+// /proc/<pid>/root behavior.
+func (s *SystemTools) SetContainerPathResolver(r ContainerPathResolver) {
+	s.pathResolver = r
 }
 
 func getClockTicks() int {
@@ -605,6 +627,9 @@ func (s *SystemTools) ContainerProcFilePath(pid int, path string) string {
 }
 
 func (s *SystemTools) ParseContainerFilePath(path string) (int, string) {
+	if s.pathResolver != nil {
+		return s.pathResolver.ParseContainerFilePath(path)
+	}
 	a := strings.Index(path, s.procDir)
 	b := strings.Index(path, "/root/")
 	if a < 0 || b < 0 {
@@ -617,6 +642,9 @@ func (s *SystemTools) ParseContainerFilePath(path string) (int, string) {
 }
 
 func (s *SystemTools) ContainerFilePath(pid int, path string) string {
+	if s.pathResolver != nil {
+		return s.pathResolver.ContainerFilePath(pid, path)
+	}
 	return fmt.Sprintf("%s%d/root%s", s.procDir, pid, path)
 }
 
@@ -1,6 +1,9 @@
 package system
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -11,3 +14,42 @@ func TestParseSharedNetNS(t *testing.T) {
 		t.Errorf("Incorrect pid: %v\n", pid)
 	}
 }
+
+// kataPathResolver simulates a Kata-style layout where the container root is
+// reached through a per-sandbox mount point instead of /proc/<pid>/root.
+type kataPathResolver struct{}
+
+func (kataPathResolver) ContainerFilePath(pid int, path string) string {
+	return fmt.Sprintf("/run/kata-containers/shared/sandboxes/%d/rootfs%s", pid, path)
+}
+
+func (kataPathResolver) ParseContainerFilePath(path string) (int, string) {
+	prefix := "/run/kata-containers/shared/sandboxes/"
+	a := strings.Index(path, prefix)
+	b := strings.Index(path, "/rootfs/")
+	if a < 0 || b < 0 {
+		return 0, ""
+	}
+	pid, _ := strconv.Atoi(path[a+len(prefix) : b])
+	return pid, path[b+len("/rootfs"):]
+}
+
+func TestContainerPathResolver(t *testing.T) {
+	sys := SystemTools{procDir: "/proc/"}
+	sys.SetContainerPathResolver(kataPathResolver{})
+
+	hostPath := sys.ContainerFilePath(4242, "/etc/passwd")
+	if hostPath != "/run/kata-containers/shared/sandboxes/4242/rootfs/etc/passwd" {
+		t.Errorf("Incorrect resolved path: %v\n", hostPath)
+	}
+
+	pid, cpath := sys.ParseContainerFilePath(hostPath)
+	if pid != 4242 || cpath != "/etc/passwd" {
+		t.Errorf("Incorrect parsed path: pid=%v path=%v\n", pid, cpath)
+	}
+
+	sys.SetContainerPathResolver(nil)
+	if pid, _ := sys.ParseContainerFilePath("/proc/11217/root/etc/passwd"); pid != 11217 {
+		t.Errorf("Incorrect pid after clearing resolver: %v\n", pid)
+	}
+}